@@ -0,0 +1,223 @@
+package solrg
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// asyncIDSeq makes request ids generated within the same nanosecond unique
+var asyncIDSeq uint64
+
+// newAsyncID returns a request id for an asynchronous Collections API call
+func newAsyncID(action string) string {
+	return fmt.Sprintf("solrg-%s-%d-%d", strings.ToLower(action), time.Now().UnixNano(), atomic.AddUint64(&asyncIDSeq, 1))
+}
+
+// AsyncOperation is a handle to an asynchronous Collections API request. Solr keeps the status of a request
+// until it is deleted with Delete or DeleteStatus, so the ID can be stored and the operation resumed later
+// with SolrClient.AsyncOperation.
+type AsyncOperation struct {
+	// ID is the async request id
+	ID string
+	// Action is the Collections API action, e.g. SPLITSHARD. Empty for resumed operations.
+	Action string
+	// PollInterval is the wait before the first status check, doubled after every check. Defaults to 250ms.
+	PollInterval time.Duration
+	// MaxPollInterval caps the wait between status checks. Defaults to 5s.
+	MaxPollInterval time.Duration
+
+	sc *SolrClient
+}
+
+// AsyncOperationError is returned by AsyncOperation.Wait when the request failed or Solr doesn't know its id
+type AsyncOperationError struct {
+	ID     string
+	Action string
+	Status *RequestStatusResponse
+}
+
+func (e *AsyncOperationError) Error() string {
+	name := e.ID
+	if e.Action != "" {
+		name = e.Action + " " + e.ID
+	}
+	msg := fmt.Sprintf("Async request %s %s: %s", name, e.Status.Status.State, e.Status.Status.Msg)
+	if e.Status.Exception.Msg != "" {
+		msg += "; " + e.Status.Exception.Msg
+	}
+	if failures := nodeFailures(e.Status.Failure); failures != "" {
+		msg += "; " + failures
+	}
+	return msg
+}
+
+// AsyncOperation returns a handle to a previously submitted asynchronous request
+func (sc *SolrClient) AsyncOperation(requestID string) *AsyncOperation {
+	return &AsyncOperation{
+		ID:              requestID,
+		PollInterval:    time.Millisecond * 250,
+		MaxPollInterval: time.Second * 5,
+		sc:              sc,
+	}
+}
+
+// collectionsAsync submits a Collections API action with a generated async request id
+func (sc *SolrClient) collectionsAsync(action string, params url.Values) (*AsyncOperation, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	op := sc.AsyncOperation(newAsyncID(action))
+	op.Action = action
+	params.Set("async", op.ID)
+	if err := sc.collectionsAPI(action, params, nil); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// Status returns the current status of the request
+func (op *AsyncOperation) Status() (*RequestStatusResponse, error) {
+	return op.sc.RequestStatus(op.ID)
+}
+
+// Wait polls the status of the request with exponential backoff until it completes, fails or ctx is done.
+// It returns the final status of a completed request, and an *AsyncOperationError if the request failed.
+// Polls that fail with a network error or a 5xx status are retried, other errors are returned. When ctx is
+// done the last status is returned with ctx.Err().
+func (op *AsyncOperation) Wait(ctx context.Context) (*RequestStatusResponse, error) {
+	interval := op.PollInterval
+	if interval <= 0 {
+		interval = time.Millisecond * 250
+	}
+	var last *RequestStatusResponse
+	for {
+		status, err := op.sc.requestStatus(ctx, op.ID)
+		if err == nil {
+			last = status
+			switch status.Status.State {
+			case "completed":
+				return status, nil
+			case "failed", "notfound":
+				return status, &AsyncOperationError{ID: op.ID, Action: op.Action, Status: status}
+			}
+		} else if ctx.Err() == nil && !retryable(err) {
+			return last, err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return last, ctx.Err()
+		case <-timer.C:
+		}
+		interval *= 2
+		if op.MaxPollInterval > 0 && interval > op.MaxPollInterval {
+			interval = op.MaxPollInterval
+		}
+	}
+}
+
+// Delete removes the stored status of the request from Solr
+func (op *AsyncOperation) Delete() error {
+	return op.sc.DeleteStatus(op.ID)
+}
+
+// DeleteStatus removes the stored status of a completed or failed asynchronous request
+func (sc *SolrClient) DeleteStatus(requestID string) error {
+	return sc.collectionsAPI("DELETESTATUS", url.Values{"requestid": {requestID}}, nil)
+}
+
+// FlushStatus removes the stored status of all completed and failed asynchronous requests
+func (sc *SolrClient) FlushStatus() error {
+	return sc.collectionsAPI("DELETESTATUS", url.Values{"flush": {"true"}}, nil)
+}
+
+// CreateCollectionAsync creates a collection asynchronously. It returns a *SolrCollectionExistsError if the
+// collection already exists.
+func (sc *SolrClient) CreateCollectionAsync(name string, numShards int, replicationFactor int) (*AsyncOperation, error) {
+	return sc.CreateCollectionAsyncWithOptions(name, CreateCollectionOptions{NumShards: numShards, ReplicationFactor: replicationFactor})
+}
+
+// CreateCollectionAsyncWithOptions is CreateCollectionAsync with the rest of the CREATE parameters.
+// opts.Timeout is not used.
+func (sc *SolrClient) CreateCollectionAsyncWithOptions(name string, opts CreateCollectionOptions) (*AsyncOperation, error) {
+	params, err := adminParams(opts, opts.Properties)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("name", name)
+	op, err := sc.collectionsAsync("CREATE", params)
+	if err != nil {
+		return nil, collectionExistsError(name, err)
+	}
+	return op, nil
+}
+
+// DeleteCollectionAsync deletes a collection asynchronously
+func (sc *SolrClient) DeleteCollectionAsync(name string) (*AsyncOperation, error) {
+	return sc.collectionsAsync("DELETE", url.Values{"name": {name}})
+}
+
+// ReloadCollectionAsync reloads a collection asynchronously
+func (sc *SolrClient) ReloadCollectionAsync(name string) (*AsyncOperation, error) {
+	return sc.collectionsAsync("RELOAD", url.Values{"name": {name}})
+}
+
+// AddReplicaAsync adds a replica asynchronously. The created core is in the Success map of the final status.
+func (sc *SolrClient) AddReplicaAsync(opts AddReplicaOptions) (*AsyncOperation, error) {
+	params, err := adminParams(opts, opts.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return sc.collectionsAsync("ADDREPLICA", params)
+}
+
+// DeleteReplicaAsync deletes replicas asynchronously
+func (sc *SolrClient) DeleteReplicaAsync(opts DeleteReplicaOptions) (*AsyncOperation, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sc.collectionsAsync("DELETEREPLICA", params)
+}
+
+// SplitShardAsync splits a shard asynchronously
+func (sc *SolrClient) SplitShardAsync(opts SplitShardOptions) (*AsyncOperation, error) {
+	params, err := adminParams(opts, opts.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return sc.collectionsAsync("SPLITSHARD", params)
+}
+
+// CreateShardAsync creates a shard asynchronously
+func (sc *SolrClient) CreateShardAsync(opts CreateShardOptions) (*AsyncOperation, error) {
+	params, err := adminParams(opts, opts.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return sc.collectionsAsync("CREATESHARD", params)
+}
+
+// DeleteShardAsync deletes a shard asynchronously
+func (sc *SolrClient) DeleteShardAsync(opts DeleteShardOptions) (*AsyncOperation, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sc.collectionsAsync("DELETESHARD", params)
+}
+
+// MigrateDocsAsync moves documents to another collection asynchronously
+func (sc *SolrClient) MigrateDocsAsync(opts MigrateDocsOptions) (*AsyncOperation, error) {
+	params, err := migrateDocsParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	return sc.collectionsAsync("MIGRATE", params)
+}
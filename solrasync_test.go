@@ -0,0 +1,179 @@
+package solrg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAsyncOperation(t *testing.T) {
+	var mu sync.Mutex
+	polls := make(map[string]int)
+	var submitted, deleted url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		mu.Lock()
+		defer mu.Unlock()
+		switch q.Get("action") {
+		case "CREATE":
+			if q.Get("name") == "exists" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"responseHeader":{"status":400,"QTime":3},"exception":{"msg":"collection already exists: exists","rspCode":400},"error":{"msg":"collection already exists: exists","code":400}}`)
+				return
+			}
+			submitted = q
+			fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":4},"requestid":%q}`, q.Get("async"))
+		case "SPLITSHARD", "ADDREPLICA", "RELOAD":
+			submitted = q
+			fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":4},"requestid":%q}`, q.Get("async"))
+		case "REQUESTSTATUS":
+			id := q.Get("requestid")
+			polls[id]++
+			switch {
+			case id == "stuck" || polls[id] < 3:
+				fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"running","msg":"found in running tasks"}}`)
+			case strings.Contains(id, "splitshard"):
+				fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"success":{"10.0.0.1:8983_solr":{"responseHeader":{"status":0,"QTime":30},"core":"a_shard1_0_replica_n1"}},"status":{"state":"completed","msg":"found in completed tasks"}}`)
+			default:
+				fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"failure":{"10.0.0.2:8983_solr":"org.apache.solr.client.solrj.SolrServerException:No space left on device"},"Operation addreplica caused exception:":"org.apache.solr.common.SolrException:Could not add replica","exception":{"msg":"Could not add replica","rspCode":500},"status":{"state":"failed","msg":"found in failed tasks"}}`)
+			}
+		case "DELETESTATUS":
+			deleted = q
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":"successfully removed stored response"}`)
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	op, err := sc.SplitShardAsync(SplitShardOptions{Collection: "a", Shard: "shard1"})
+	must(err)
+	if submitted.Get("async") != op.ID || submitted.Get("shard") != "shard1" || op.Action != "SPLITSHARD" {
+		t.Fatalf("Unexpected submission %v for %+v", submitted, op)
+	}
+	op.PollInterval = time.Millisecond
+	status, err := op.Wait(ctx)
+	must(err)
+	if polls[op.ID] != 3 || status.Success["10.0.0.1:8983_solr"].Core != "a_shard1_0_replica_n1" {
+		t.Errorf("Unexpected status %+v after %d polls", status, polls[op.ID])
+	}
+	must(op.Delete())
+	if deleted.Get("requestid") != op.ID {
+		t.Errorf("Unexpected delete parameters %v", deleted)
+	}
+
+	other, err := sc.ReloadCollectionAsync("a")
+	must(err)
+	if other.ID == op.ID {
+		t.Errorf("Request ids are not unique: %s", op.ID)
+	}
+
+	created, err := sc.CreateCollectionAsync("new", 2, 1)
+	must(err)
+	if submitted.Get("async") != created.ID || submitted.Get("numShards") != "2" || submitted.Get("replicationFactor") != "1" {
+		t.Errorf("Unexpected create parameters %v", submitted)
+	}
+	if _, err := sc.CreateCollectionAsync("exists", 1, 1); err == nil || err.Error() != "Collection exists already exists" {
+		t.Errorf("Expected a *SolrCollectionExistsError, got %T: %v", err, err)
+	}
+	_, err = sc.CreateCollectionAsyncWithOptions("new", CreateCollectionOptions{NumShards: 1, ConfigName: "products"})
+	must(err)
+	if submitted.Get("collection.configName") != "products" || submitted.Get("replicationFactor") != "" {
+		t.Errorf("Unexpected create parameters %v", submitted)
+	}
+
+	op, err = sc.AddReplicaAsync(AddReplicaOptions{Collection: "a", Shard: "shard1"})
+	must(err)
+	op.PollInterval = time.Millisecond
+	status, err = op.Wait(ctx)
+	var ae *AsyncOperationError
+	if !errors.As(err, &ae) || status.Status.State != "failed" {
+		t.Fatalf("Expected an *AsyncOperationError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Could not add replica") || !strings.Contains(err.Error(), "No space left on device") {
+		t.Errorf("Unexpected error message %s", err)
+	}
+
+	stuck := sc.AsyncOperation("stuck")
+	stuck.PollInterval = time.Millisecond
+	stuck.MaxPollInterval = time.Millisecond * 5
+	short, cancelShort := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancelShort()
+	if _, err := stuck.Wait(short); err != context.DeadlineExceeded {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+
+	must(sc.FlushStatus())
+	if deleted.Get("flush") != "true" {
+		t.Errorf("Unexpected flush parameters %v", deleted)
+	}
+}
+
+func TestAsyncOperationWaitRetries(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("requestid") {
+		case "flaky":
+			mu.Lock()
+			polls++
+			n := polls
+			mu.Unlock()
+			switch n {
+			case 1:
+				// drop the connection without a response
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			case 2:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"completed","msg":"found in completed tasks"}}`)
+			}
+		case "slow":
+			<-release
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"msg":"missing requestid","code":400}}`)
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	flaky := sc.AsyncOperation("flaky")
+	flaky.PollInterval = time.Millisecond
+	status, err := flaky.Wait(ctx)
+	must(err)
+	if status.Status.State != "completed" || polls != 3 {
+		t.Errorf("Expected the poll errors to be retried, got %+v after %d polls", status, polls)
+	}
+
+	if _, err := sc.AsyncOperation("").Wait(ctx); !IsBadRequest(err) {
+		t.Errorf("Expected the bad request to be returned, got %v", err)
+	}
+
+	short, cancelShort := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancelShort()
+	start := time.Now()
+	if _, err := sc.AsyncOperation("slow").Wait(short); err != context.DeadlineExceeded {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait didn't cancel the pending poll, took %s", elapsed)
+	}
+}
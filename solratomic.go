@@ -0,0 +1,95 @@
+package solrg
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// AtomicUpdate is a Solr atomic update operation on a single field.
+// See https://lucene.apache.org/solr/guide/7_4/updating-parts-of-documents.html
+type AtomicUpdate struct {
+	Op    string
+	Value interface{}
+}
+
+// MarshalJSON serializes the operation as {"op": value}
+func (au AtomicUpdate) MarshalJSON() ([]byte, error) {
+	val, err := solrValue(reflect.ValueOf(au.Value))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{au.Op: val})
+}
+
+// AtomicSet replaces the value of a field. A nil value removes the field.
+func AtomicSet(value interface{}) AtomicUpdate {
+	return AtomicUpdate{Op: "set", Value: value}
+}
+
+// AtomicInc increments a numeric field
+func AtomicInc(n interface{}) AtomicUpdate {
+	return AtomicUpdate{Op: "inc", Value: n}
+}
+
+// AtomicAdd adds values to a multivalued field
+func AtomicAdd(value interface{}) AtomicUpdate {
+	return AtomicUpdate{Op: "add", Value: value}
+}
+
+// AtomicAddDistinct adds values to a multivalued field only if they are not already present
+func AtomicAddDistinct(value interface{}) AtomicUpdate {
+	return AtomicUpdate{Op: "add-distinct", Value: value}
+}
+
+// AtomicRemove removes all occurrences of values from a multivalued field
+func AtomicRemove(value interface{}) AtomicUpdate {
+	return AtomicUpdate{Op: "remove", Value: value}
+}
+
+// AtomicRemoveRegex removes all occurrences of values matching a regular expression from a multivalued field
+func AtomicRemoveRegex(pattern interface{}) AtomicUpdate {
+	return AtomicUpdate{Op: "removeregex", Value: pattern}
+}
+
+// SetAtomic replaces the value of a field in the indexed document. A nil value removes the field.
+func (sd *SolrDocument) SetAtomic(name string, value interface{}) {
+	sd.fields[name] = AtomicSet(value)
+}
+
+// Inc increments a numeric field in the indexed document by n
+func (sd *SolrDocument) Inc(name string, n int64) {
+	sd.fields[name] = AtomicInc(n)
+}
+
+// AddToField adds values to a multivalued field in the indexed document
+func (sd *SolrDocument) AddToField(name string, value interface{}) {
+	sd.fields[name] = AtomicAdd(value)
+}
+
+// AddDistinct adds values to a multivalued field in the indexed document if they aren't already present
+func (sd *SolrDocument) AddDistinct(name string, value interface{}) {
+	sd.fields[name] = AtomicAddDistinct(value)
+}
+
+// Remove removes values from a multivalued field in the indexed document
+func (sd *SolrDocument) Remove(name string, value interface{}) {
+	sd.fields[name] = AtomicRemove(value)
+}
+
+// RemoveRegex removes values matching a regular expression from a multivalued field in the indexed document
+func (sd *SolrDocument) RemoveRegex(name string, pattern string) {
+	sd.fields[name] = AtomicRemoveRegex(pattern)
+}
+
+// UpdateFields applies atomic updates to a single document without re-sending the full record
+func (sc *SolrClient) UpdateFields(collection string, id string, ops map[string]AtomicUpdate, opts ...UpdateOption) error {
+	doc := NewSolrDocument(id)
+	for name, op := range ops {
+		doc.Set(name, op)
+	}
+	docs := NewSolrDocumentCollection()
+	if err := docs.AddDoc(doc); err != nil {
+		return err
+	}
+	return sc.PostDocs(&docs, collection, opts...)
+}
@@ -0,0 +1,177 @@
+package solrg
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+// BackupOptions holds the optional parameters of Backup
+type BackupOptions struct {
+	// Incremental only copies index files changed since the previous backup with the same name and location.
+	// Solr 9 makes incremental backups by default, Solr 8.9 and later when set. Older versions ignore it.
+	Incremental *bool `url:"incremental,omitempty"`
+	// MaxNumBackupPoints deletes the oldest incremental backups beyond this number
+	MaxNumBackupPoints int `url:"maxNumBackupPoints,omitempty"`
+	// CommitName backs up a named snapshot instead of the latest commit
+	CommitName string `url:"commitName,omitempty"`
+	// IndexBackup is copy-files (the default) or none, to back up only the configuration
+	IndexBackup string `url:"indexBackup,omitempty"`
+}
+
+// BackupDetails describes an incremental backup. Full backups leave it empty.
+type BackupDetails struct {
+	Collection             string      `json:"collection"`
+	NumShards              int         `json:"numShards"`
+	BackupID               int         `json:"backupId"`
+	IndexVersion           string      `json:"indexVersion"`
+	StartTime              string      `json:"startTime"`
+	EndTime                string      `json:"endTime"`
+	IndexFileCount         int         `json:"indexFileCount"`
+	UploadedIndexFileCount int         `json:"uploadedIndexFileCount"`
+	IndexSizeMB            json.Number `json:"indexSizeMB"`
+	UploadedIndexFileMB    json.Number `json:"uploadedIndexFileMB"`
+	ShardBackupIDs         []string    `json:"shardBackupIds"`
+}
+
+// BackupResponse is the response of Backup
+type BackupResponse struct {
+	CollectionsAPIResponse
+	Response BackupDetails `json:"response"`
+}
+
+// backupParams encodes the parameters shared by the backup actions
+func backupParams(opts interface{}, properties map[string]string, name string, location string, repository string) (url.Values, error) {
+	params, err := adminParams(opts, properties)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("name", name)
+	if location != "" {
+		params.Set("location", location)
+	}
+	if repository != "" {
+		params.Set("repository", repository)
+	}
+	return params, nil
+}
+
+// Backup backs up a collection to a location of a backup repository. An empty repository uses the default
+// local file system repository, whose location must be on a shared drive mounted on every node. Backups of
+// large collections take longer than the HTTP timeout, use BackupAsync for those.
+func (sc *SolrClient) Backup(collection string, name string, location string, repository string, opts BackupOptions) (*BackupResponse, error) {
+	params, err := backupParams(opts, nil, name, location, repository)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("collection", collection)
+	var resp BackupResponse
+	if err := sc.collectionsAPI("BACKUP", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// BackupAsync backs up a collection asynchronously. See Backup.
+func (sc *SolrClient) BackupAsync(collection string, name string, location string, repository string, opts BackupOptions) (*AsyncOperation, error) {
+	params, err := backupParams(opts, nil, name, location, repository)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("collection", collection)
+	return sc.collectionsAsync("BACKUP", params)
+}
+
+// RestoreOptions holds the optional parameters of Restore. Replica counts default to the ones of the backed
+// up collection.
+type RestoreOptions struct {
+	// BackupID restores an older incremental backup instead of the latest
+	BackupID          *int     `url:"backupId,omitempty"`
+	ConfigName        string   `url:"collection.configName,omitempty"`
+	ReplicationFactor int      `url:"replicationFactor,omitempty"`
+	NrtReplicas       int      `url:"nrtReplicas,omitempty"`
+	TlogReplicas      int      `url:"tlogReplicas,omitempty"`
+	PullReplicas      int      `url:"pullReplicas,omitempty"`
+	MaxShardsPerNode  int      `url:"maxShardsPerNode,omitempty"`
+	CreateNodeSet     []string `url:"createNodeSet,comma,omitempty"`
+	// Properties sets core properties of the restored replicas
+	Properties map[string]string `url:"-"`
+}
+
+// Restore restores a backup to a collection, which must not exist, or may exist with the same number of
+// shards on Solr 8.9 and later. Restores of large collections take longer than the HTTP timeout, use
+// RestoreAsync for those.
+func (sc *SolrClient) Restore(collection string, name string, location string, repository string, opts RestoreOptions) (*CollectionsAPIResponse, error) {
+	params, err := backupParams(opts, opts.Properties, name, location, repository)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("collection", collection)
+	var resp CollectionsAPIResponse
+	return partialResponse(&resp, sc.collectionsAPI("RESTORE", params, &resp))
+}
+
+// RestoreAsync restores a backup asynchronously. See Restore.
+func (sc *SolrClient) RestoreAsync(collection string, name string, location string, repository string, opts RestoreOptions) (*AsyncOperation, error) {
+	params, err := backupParams(opts, opts.Properties, name, location, repository)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("collection", collection)
+	return sc.collectionsAsync("RESTORE", params)
+}
+
+// BackupPoint is one incremental backup of a collection
+type BackupPoint struct {
+	BackupID        int               `json:"backupId"`
+	Collection      string            `json:"collection"`
+	CollectionAlias string            `json:"collectionAlias"`
+	ConfigName      string            `json:"collection.configName"`
+	IndexVersion    string            `json:"indexVersion"`
+	StartTime       string            `json:"startTime"`
+	EndTime         string            `json:"endTime"`
+	IndexFileCount  int               `json:"indexFileCount"`
+	IndexSizeMB     json.Number       `json:"indexSizeMB"`
+	ShardBackupIDs  map[string]string `json:"shardBackupIds"`
+}
+
+// ListBackupsResponse lists the incremental backups stored under a name
+type ListBackupsResponse struct {
+	CollectionsResponse
+	Collection string        `json:"collection"`
+	Backups    []BackupPoint `json:"backups"`
+}
+
+// ListBackups lists the incremental backups stored under a name and location. It requires Solr 8.9 or later.
+func (sc *SolrClient) ListBackups(name string, location string, repository string) (*ListBackupsResponse, error) {
+	params, err := backupParams(struct{}{}, nil, name, location, repository)
+	if err != nil {
+		return nil, err
+	}
+	var resp ListBackupsResponse
+	if err := sc.collectionsAPI("LISTBACKUP", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteBackupOptions selects the incremental backups deleted by DeleteBackup. Set exactly one option.
+type DeleteBackupOptions struct {
+	BackupID *int `url:"backupId,omitempty"`
+	// MaxNumBackupPoints keeps the most recent backups and deletes the others
+	MaxNumBackupPoints int `url:"maxNumBackupPoints,omitempty"`
+	// PurgeUnused deletes index files no longer referenced by any backup
+	PurgeUnused bool `url:"purgeUnused,omitempty"`
+}
+
+// DeleteBackup deletes incremental backups stored under a name and location. It requires Solr 8.9 or later.
+func (sc *SolrClient) DeleteBackup(name string, location string, repository string, opts DeleteBackupOptions) (*CollectionsResponse, error) {
+	params, err := backupParams(opts, nil, name, location, repository)
+	if err != nil {
+		return nil, err
+	}
+	var resp CollectionsResponse
+	if err := sc.collectionsAPI("DELETEBACKUP", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,136 @@
+package solrg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collectionsStub is a Collections API stub that records the issued requests and answers with canned
+// responses by action
+type collectionsStub struct {
+	*httptest.Server
+	mu        sync.Mutex
+	requests  []url.Values
+	responses map[string]string
+}
+
+func newCollectionsStub(responses map[string]string) *collectionsStub {
+	stub := &collectionsStub{responses: responses}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/collections" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		q := r.URL.Query()
+		stub.mu.Lock()
+		stub.requests = append(stub.requests, q)
+		stub.mu.Unlock()
+		if resp, ok := stub.responses[q.Get("action")]; ok {
+			fmt.Fprint(w, resp)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"error":{"msg":"Unknown action: %s","code":400}}`, q.Get("action"))
+	}))
+	return stub
+}
+
+// client returns a direct client for the stub
+func (stub *collectionsStub) client() *SolrClient {
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(stub.URL, "http://"))
+	must(err)
+	return sc
+}
+
+// last returns the parameters of the last request for an action
+func (stub *collectionsStub) last(action string) url.Values {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	for i := len(stub.requests) - 1; i >= 0; i-- {
+		if stub.requests[i].Get("action") == action {
+			return stub.requests[i]
+		}
+	}
+	return nil
+}
+
+// expectParams checks the parameters of a request. An empty expected value means the parameter must be absent.
+func expectParams(t *testing.T, params url.Values, expected map[string]string) {
+	t.Helper()
+	for k, v := range expected {
+		if params.Get(k) != v {
+			t.Errorf("Expected %s=%q, got %q in %v", k, v, params.Get(k), params)
+		}
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	stub := newCollectionsStub(map[string]string{
+		"BACKUP": `{"responseHeader":{"status":0,"QTime":1800},"response":{"collection":"products","numShards":2,"backupId":3,
+			"indexVersion":"9.4.0","startTime":"2023-11-02T10:00:00.000Z","indexFileCount":40,"uploadedIndexFileCount":6,
+			"indexSizeMB":12.5,"uploadedIndexFileMB":1.25,"shardBackupIds":["md_shard1_3.json","md_shard2_3.json"],"endTime":"2023-11-02T10:00:01.800Z"}}`,
+		"RESTORE":       `{"responseHeader":{"status":0,"QTime":4000},"success":{"10.0.0.1:8983_solr":{"responseHeader":{"status":0,"QTime":90},"core":"restored_shard1_replica_n1"}}}`,
+		"REQUESTSTATUS": `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"completed","msg":"found in completed tasks"}}`,
+		"LISTBACKUP": `{"responseHeader":{"status":0,"QTime":3},"collection":"products","backups":[
+			{"indexFileCount":38,"indexSizeMB":11.9,"shardBackupIds":{"shard1":"md_shard1_0.json"},"collection.configName":"products","backupId":0,"collectionAlias":"products","startTime":"2023-11-01T10:00:00.000Z","indexVersion":"9.4.0"},
+			{"indexFileCount":40,"indexSizeMB":12.5,"shardBackupIds":{"shard1":"md_shard1_3.json"},"collection.configName":"products","backupId":3,"collectionAlias":"products","startTime":"2023-11-02T10:00:00.000Z","indexVersion":"9.4.0"}]}`,
+		"DELETEBACKUP": `{"responseHeader":{"status":0,"QTime":30},"deleted":[{"backupId":0}]}`,
+	})
+	defer stub.Close()
+	sc := stub.client()
+
+	resp, err := sc.Backup("products", "nightly", "/mnt/backups", "s3", BackupOptions{Incremental: Bool(true), MaxNumBackupPoints: 7})
+	must(err)
+	expectParams(t, stub.last("BACKUP"), map[string]string{"collection": "products", "name": "nightly", "location": "/mnt/backups",
+		"repository": "s3", "incremental": "true", "maxNumBackupPoints": "7", "commitName": "", "async": ""})
+	if resp.Response.BackupID != 3 || resp.Response.UploadedIndexFileCount != 6 || resp.Response.UploadedIndexFileMB != "1.25" || len(resp.Response.ShardBackupIDs) != 2 {
+		t.Errorf("Unexpected backup response %+v", resp.Response)
+	}
+
+	_, err = sc.Backup("products", "full", "/mnt/backups", "", BackupOptions{})
+	must(err)
+	expectParams(t, stub.last("BACKUP"), map[string]string{"name": "full", "repository": "", "incremental": ""})
+
+	op, err := sc.BackupAsync("products", "nightly", "/mnt/backups", "s3", BackupOptions{})
+	must(err)
+	expectParams(t, stub.last("BACKUP"), map[string]string{"async": op.ID})
+	op.PollInterval = time.Millisecond
+	_, err = op.Wait(context.Background())
+	must(err)
+	expectParams(t, stub.last("REQUESTSTATUS"), map[string]string{"requestid": op.ID})
+
+	restored, err := sc.Restore("restored", "nightly", "/mnt/backups", "s3", RestoreOptions{BackupID: Int(0), ConfigName: "products_v2", NrtReplicas: 2})
+	must(err)
+	expectParams(t, stub.last("RESTORE"), map[string]string{"collection": "restored", "name": "nightly", "backupId": "0",
+		"collection.configName": "products_v2", "nrtReplicas": "2", "replicationFactor": ""})
+	if restored.Success["10.0.0.1:8983_solr"].Core != "restored_shard1_replica_n1" {
+		t.Errorf("Unexpected restore response %+v", restored)
+	}
+
+	op, err = sc.RestoreAsync("restored", "nightly", "/mnt/backups", "s3", RestoreOptions{Properties: map[string]string{"dataDir": "/data"}})
+	must(err)
+	expectParams(t, stub.last("RESTORE"), map[string]string{"async": op.ID, "property.dataDir": "/data", "backupId": ""})
+
+	backups, err := sc.ListBackups("nightly", "/mnt/backups", "s3")
+	must(err)
+	expectParams(t, stub.last("LISTBACKUP"), map[string]string{"name": "nightly", "location": "/mnt/backups", "repository": "s3", "collection": ""})
+	if len(backups.Backups) != 2 || backups.Backups[1].BackupID != 3 || backups.Backups[1].ConfigName != "products" || backups.Backups[0].ShardBackupIDs["shard1"] != "md_shard1_0.json" {
+		t.Errorf("Unexpected backups %+v", backups)
+	}
+
+	_, err = sc.DeleteBackup("nightly", "/mnt/backups", "s3", DeleteBackupOptions{MaxNumBackupPoints: 5})
+	must(err)
+	expectParams(t, stub.last("DELETEBACKUP"), map[string]string{"name": "nightly", "maxNumBackupPoints": "5", "backupId": "", "purgeUnused": ""})
+
+	delete(stub.responses, "LISTBACKUP")
+	if _, err := sc.ListBackups("nightly", "/mnt/backups", ""); !IsBadRequest(err) {
+		t.Errorf("Expected a bad request, got %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package solrg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BulkIndexerConfig configures a BulkIndexer. Zero values are replaced with sensible defaults.
+type BulkIndexerConfig struct {
+	// Collection is the collection documents are indexed into
+	Collection string
+	// NumWorkers is the number of batches sent to Solr concurrently. Defaults to the number of CPUs.
+	NumWorkers int
+	// FlushDocs is the number of buffered documents that triggers a flush. Defaults to 1000.
+	FlushDocs int
+	// FlushBytes is the size of buffered json that triggers a flush. Defaults to 5MB.
+	FlushBytes int
+	// FlushInterval is how often buffered documents are flushed regardless of size. Defaults to 30s.
+	FlushInterval time.Duration
+	// MaxRetries is the number of times a failed batch is retried. Zero means failed batches are not retried.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubled on every subsequent attempt. Defaults to 1s.
+	RetryBackoff time.Duration
+	// Timeout is the timeout of each update request. Defaults to 60s.
+	Timeout time.Duration
+	// CommitWithin asks Solr to commit each batch within this duration. Zero leaves commits to the caller.
+	CommitWithin time.Duration
+	// Options are applied to every update request, e.g. MaxErrors to report failed documents per batch
+	// in BulkIndexerBatch.Response.Errors instead of failing the whole batch
+	Options []UpdateOption
+	// OnSuccess is called from a worker goroutine after a batch is indexed, including batches with documents
+	// that failed within MaxErrors
+	OnSuccess func(batch *BulkIndexerBatch)
+	// OnFailure is called from a worker goroutine when a batch fails after all retries
+	OnFailure func(batch *BulkIndexerBatch)
+}
+
+// BulkIndexerBatch describes a batch of documents sent by a BulkIndexer
+type BulkIndexerBatch struct {
+	Docs     []SolrDocument
+	Bytes    int
+	Attempts int
+	Duration time.Duration
+	Err      error
+	// Response is Solr's response to the last attempt, nil if it failed
+	Response *UpdateResponse
+
+	body []byte
+}
+
+// BulkIndexerStats holds counters for a BulkIndexer
+type BulkIndexerStats struct {
+	NumAdded   uint64
+	NumIndexed uint64
+	// NumFailed counts the documents of failed batches and those that failed within MaxErrors
+	NumFailed   uint64
+	NumBatches  uint64
+	NumRequests uint64
+	NumRetries  uint64
+}
+
+// BulkIndexer buffers documents and indexes them in batches using a pool of concurrent workers.
+// When all workers are busy Add blocks until a batch has been sent, so producers can't outrun Solr.
+// Documents are flushed when the buffer reaches FlushDocs documents or FlushBytes bytes, every
+// FlushInterval, and on Close.
+type BulkIndexer struct {
+	// stats is first to keep its counters 64-bit aligned for atomic access
+	stats BulkIndexerStats
+
+	sc     *SolrClient
+	config BulkIndexerConfig
+	client *http.Client
+	params url.Values
+
+	mu      sync.Mutex
+	buf     []SolrDocument
+	bufJSON [][]byte
+	bufSize int
+	closed  bool
+	// sending is held for reading while a batch is being queued so Close doesn't close the channel under it
+	sending sync.RWMutex
+
+	batches chan *BulkIndexerBatch
+	done    chan struct{}
+	workers sync.WaitGroup
+	ticker  sync.WaitGroup
+}
+
+// NewBulkIndexer creates a BulkIndexer and starts its workers. Close must be called to flush
+// the remaining documents and stop the workers.
+func (sc *SolrClient) NewBulkIndexer(config BulkIndexerConfig) (*BulkIndexer, error) {
+	if config.Collection == "" {
+		return nil, fmt.Errorf("BulkIndexerConfig.Collection is required")
+	}
+	if config.NumWorkers <= 0 {
+		config.NumWorkers = runtime.NumCPU()
+	}
+	if config.FlushDocs <= 0 {
+		config.FlushDocs = 1000
+	}
+	if config.FlushBytes <= 0 {
+		config.FlushBytes = 5 * 1024 * 1024
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 30 * time.Second
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 60 * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = config.NumWorkers
+
+	bi := &BulkIndexer{
+		sc:      sc,
+		config:  config,
+		client:  &http.Client{Timeout: config.Timeout, Transport: transport},
+		params:  updateParams(config.Options),
+		batches: make(chan *BulkIndexerBatch, config.NumWorkers),
+		done:    make(chan struct{}),
+	}
+
+	if config.CommitWithin > 0 {
+		CommitWithin(config.CommitWithin)(bi.params)
+	}
+
+	for i := 0; i < config.NumWorkers; i++ {
+		bi.workers.Add(1)
+		go bi.worker()
+	}
+	bi.ticker.Add(1)
+	go bi.flushPeriodically()
+	return bi, nil
+}
+
+// Add buffers a document for indexing. It blocks while all workers are busy and the queue is full.
+func (bi *BulkIndexer) Add(doc SolrDocument) error {
+	if err := validateDocIDs(doc); err != nil {
+		return err
+	}
+	jsn, err := doc.SolrJSON()
+	if err != nil {
+		return err
+	}
+
+	bi.mu.Lock()
+	if bi.closed {
+		bi.mu.Unlock()
+		return fmt.Errorf("BulkIndexer is closed")
+	}
+	bi.buf = append(bi.buf, doc)
+	bi.bufJSON = append(bi.bufJSON, []byte(jsn))
+	bi.bufSize += len(jsn) + 1
+	var batch *BulkIndexerBatch
+	if len(bi.buf) >= bi.config.FlushDocs || bi.bufSize >= bi.config.FlushBytes {
+		batch = bi.takeBatch()
+	}
+	if batch != nil {
+		bi.sending.RLock()
+	}
+	bi.mu.Unlock()
+
+	atomic.AddUint64(&bi.stats.NumAdded, 1)
+	if batch != nil {
+		bi.batches <- batch
+		bi.sending.RUnlock()
+	}
+	return nil
+}
+
+// Flush queues the buffered documents for indexing without waiting for them to be sent
+func (bi *BulkIndexer) Flush() {
+	bi.mu.Lock()
+	if bi.closed {
+		bi.mu.Unlock()
+		return
+	}
+	batch := bi.takeBatch()
+	if batch == nil {
+		bi.mu.Unlock()
+		return
+	}
+	bi.sending.RLock()
+	bi.mu.Unlock()
+	bi.batches <- batch
+	bi.sending.RUnlock()
+}
+
+// Close flushes the buffered documents, waits for all batches to be sent and stops the workers.
+// An error is returned if any documents failed to index.
+func (bi *BulkIndexer) Close() error {
+	bi.mu.Lock()
+	if bi.closed {
+		bi.mu.Unlock()
+		return fmt.Errorf("BulkIndexer is already closed")
+	}
+	bi.closed = true
+	batch := bi.takeBatch()
+	bi.mu.Unlock()
+
+	close(bi.done)
+	bi.ticker.Wait()
+	if batch != nil {
+		bi.batches <- batch
+	}
+	bi.sending.Lock()
+	close(bi.batches)
+	bi.sending.Unlock()
+	bi.workers.Wait()
+
+	if failed := atomic.LoadUint64(&bi.stats.NumFailed); failed > 0 {
+		return fmt.Errorf("%d documents failed to index", failed)
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the indexer's counters
+func (bi *BulkIndexer) Stats() BulkIndexerStats {
+	return BulkIndexerStats{
+		NumAdded:    atomic.LoadUint64(&bi.stats.NumAdded),
+		NumIndexed:  atomic.LoadUint64(&bi.stats.NumIndexed),
+		NumFailed:   atomic.LoadUint64(&bi.stats.NumFailed),
+		NumBatches:  atomic.LoadUint64(&bi.stats.NumBatches),
+		NumRequests: atomic.LoadUint64(&bi.stats.NumRequests),
+		NumRetries:  atomic.LoadUint64(&bi.stats.NumRetries),
+	}
+}
+
+// takeBatch empties the buffer into a new batch. bi.mu must be held.
+func (bi *BulkIndexer) takeBatch() *BulkIndexerBatch {
+	if len(bi.buf) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	body.Grow(bi.bufSize + 2)
+	body.WriteByte('[')
+	for i, jsn := range bi.bufJSON {
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.Write(jsn)
+	}
+	body.WriteByte(']')
+
+	batch := &BulkIndexerBatch{Docs: bi.buf, Bytes: body.Len()}
+	batch.body = body.Bytes()
+	bi.buf = nil
+	bi.bufJSON = nil
+	bi.bufSize = 0
+	return batch
+}
+
+func (bi *BulkIndexer) flushPeriodically() {
+	defer bi.ticker.Done()
+	t := time.NewTicker(bi.config.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			bi.Flush()
+		case <-bi.done:
+			return
+		}
+	}
+}
+
+func (bi *BulkIndexer) worker() {
+	defer bi.workers.Done()
+	for batch := range bi.batches {
+		bi.send(batch)
+	}
+}
+
+// send indexes a batch, retrying with exponential backoff
+func (bi *BulkIndexer) send(batch *BulkIndexerBatch) {
+	start := time.Now()
+	backoff := bi.config.RetryBackoff
+	for {
+		batch.Attempts++
+		atomic.AddUint64(&bi.stats.NumRequests, 1)
+		batch.Response, batch.Err = bi.sc.postUpdate(bi.client, bi.config.Collection, bi.params, bytes.NewReader(batch.body))
+		if batch.Err == nil || batch.Attempts > bi.config.MaxRetries || !retryable(batch.Err) {
+			break
+		}
+		atomic.AddUint64(&bi.stats.NumRetries, 1)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	batch.Duration = time.Since(start)
+	batch.body = nil
+
+	atomic.AddUint64(&bi.stats.NumBatches, 1)
+	if batch.Err != nil {
+		atomic.AddUint64(&bi.stats.NumFailed, uint64(len(batch.Docs)))
+		if bi.config.OnFailure != nil {
+			bi.config.OnFailure(batch)
+		}
+		return
+	}
+	// documents that failed within MaxErrors don't fail the batch
+	failed := len(batch.Response.Errors)
+	if failed > len(batch.Docs) {
+		failed = len(batch.Docs)
+	}
+	atomic.AddUint64(&bi.stats.NumIndexed, uint64(len(batch.Docs)-failed))
+	atomic.AddUint64(&bi.stats.NumFailed, uint64(failed))
+	if bi.config.OnSuccess != nil {
+		bi.config.OnSuccess(batch)
+	}
+}
+
+// retryable returns false for errors that will happen again if the same batch is resent, i.e. Solr rejecting
+// the request with a 4xx status. Network errors and 5xx statuses are retried.
+func retryable(err error) bool {
+	var se *SolrError
+	if !errors.As(err, &se) {
+		return true
+	}
+	switch se.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return se.StatusCode < 400 || se.StatusCode >= 500
+}
@@ -0,0 +1,108 @@
+package solrg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBulkIndexer(t *testing.T) {
+	var received, requests int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fail the first request to exercise retries
+		if atomic.AddInt64(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var docs []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&docs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		atomic.AddInt64(&received, int64(len(docs)))
+		fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	var mu sync.Mutex
+	var batchSizes []int
+	bi, err := sc.NewBulkIndexer(BulkIndexerConfig{
+		Collection:   "test",
+		NumWorkers:   3,
+		FlushDocs:    100,
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+		OnSuccess: func(batch *BulkIndexerBatch) {
+			mu.Lock()
+			batchSizes = append(batchSizes, len(batch.Docs))
+			mu.Unlock()
+		},
+		OnFailure: func(batch *BulkIndexerBatch) {
+			t.Errorf("Batch failed after %d attempts: %s", batch.Attempts, batch.Err)
+		},
+	})
+	must(err)
+
+	for i := 0; i < 1050; i++ {
+		doc := NewSolrDocument(fmt.Sprintf("%d", i))
+		doc.SetInt("n_i", int64(i))
+		must(bi.Add(doc))
+	}
+	must(bi.Close())
+
+	if received != 1050 {
+		t.Errorf("Expected Solr to receive 1050 docs but it received %d", received)
+	}
+	if len(batchSizes) != 11 {
+		t.Errorf("Expected 11 batches but there were %d", len(batchSizes))
+	}
+	stats := bi.Stats()
+	if stats.NumAdded != 1050 || stats.NumIndexed != 1050 || stats.NumFailed != 0 || stats.NumRetries != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if err := bi.Add(NewSolrDocument("late")); err == nil {
+		t.Error("Expected an error adding a doc after Close")
+	}
+}
+
+func TestBulkIndexerToleratedErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"responseHeader":{"errors":[{"type":"ADD","id":"3","message":"ERROR: [doc=3] bad value"}],"maxErrors":-1,"status":0,"QTime":1}}`)
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	var tolerated []DocumentError
+	bi, err := sc.NewBulkIndexer(BulkIndexerConfig{
+		Collection: "test",
+		NumWorkers: 1,
+		FlushDocs:  10,
+		Options:    []UpdateOption{MaxErrors(-1)},
+		OnSuccess: func(batch *BulkIndexerBatch) {
+			tolerated = append(tolerated, batch.Response.Errors...)
+		},
+	})
+	must(err)
+	for i := 0; i < 10; i++ {
+		must(bi.Add(NewSolrDocument(fmt.Sprintf("%d", i))))
+	}
+	if err := bi.Close(); err == nil || err.Error() != "1 documents failed to index" {
+		t.Errorf("Expected Close to report the failed document, got %v", err)
+	}
+
+	stats := bi.Stats()
+	if stats.NumIndexed != 9 || stats.NumFailed != 1 || len(tolerated) != 1 || tolerated[0].ID != "3" {
+		t.Errorf("Unexpected stats %+v with errors %v", stats, tolerated)
+	}
+}
@@ -1,13 +1,18 @@
 package solrg
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-querystring/query"
@@ -35,20 +40,30 @@ func NewDirectSolrClient(solrUrl string) (*SolrClient, error) {
 	return &sc, nil
 }
 
+// SolrCollectionExistsError is returned when creating a collection that already exists
 type SolrCollectionExistsError struct {
 	collectionName string
+	// Err is Solr's error response
+	Err *SolrError
 }
 
 func (e *SolrCollectionExistsError) Error() string {
 	return fmt.Sprintf("Collection %s already exists", e.collectionName)
 }
 
-// SolrClient Solr Client struct
+// Unwrap returns Solr's error response
+func (e *SolrCollectionExistsError) Unwrap() error {
+	return e.Err
+}
+
+// SolrClient Solr Client struct. A SolrClient is safe for concurrent use.
 type SolrClient struct {
 	liveNodes     LiveNodes
 	lastNodeIndex int
 	numNodes      int
 	Connection    *zk.Conn
+	mu            sync.Mutex
+	javabin       bool
 }
 
 // LiveNodes struct to hold slice of live nodes and when the last time live nodes were updated
@@ -67,6 +82,12 @@ func (sc *SolrClient) Connect(zksString string) error {
 
 // LiveSolrNodes returns a slice of urls to live Solr nodes
 func (sc *SolrClient) LiveSolrNodes() (*LiveNodes, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.liveSolrNodes()
+}
+
+func (sc *SolrClient) liveSolrNodes() (*LiveNodes, error) {
 	//only check for new nodes every 5 seconds
 	duration := time.Since(sc.liveNodes.LastUpdate)
 	if duration.Seconds() > 5 {
@@ -93,6 +114,10 @@ func (sc *SolrClient) Query(collection string, reqHandler string, params *SolrPa
 
 	params.JSONNl = "arrntv"
 	v, _ := query.Values(params)
+	javabin := sc.usesJavabin()
+	if javabin {
+		v.Set("wt", "javabin")
+	}
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(v.Encode())))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
@@ -106,11 +131,17 @@ func (sc *SolrClient) Query(collection string, reqHandler string, params *SolrPa
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Error in Solr response, status code = %d, full error:\n%s", resp.StatusCode, body)
+		return nil, readSolrError(resp)
 	}
 
 	// we have a successful request if we made it this far
+	if javabin {
+		val, err := NewJavabinDecoder(resp.Body).Decode()
+		if err != nil {
+			return nil, fmt.Errorf("Error decoding javabin response: %s", err)
+		}
+		return javabinSearchResponse(val)
+	}
 	var solrResp SolrSearchResponse
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -126,9 +157,11 @@ func (sc *SolrClient) Query(collection string, reqHandler string, params *SolrPa
 
 // LBNodeAddress Returns a node address using simple round robin LB of available nodes
 func (sc *SolrClient) LBNodeAddress() string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
 	// start back at 0
 	if sc.numNodes == 0 {
-		sc.LiveSolrNodes()
+		sc.liveSolrNodes()
 	}
 	if sc.numNodes == 1 {
 		return sc.liveNodes.Nodes[0]
@@ -146,22 +179,102 @@ func (sc *SolrClient) LBNodeAddress() string {
 
 // Commit executes a Solr commit command
 func (sc *SolrClient) Commit(collectionName string) error {
+	return sc.CommitWithOptions(collectionName, CommitOptions{})
+}
 
-	//http://localhost:8983/solr/techproducts/update?commit=true
-	url := "http://" + sc.LBNodeAddress() + "/" + collectionName + "/update?commit=true"
+// CommitOptions control how a commit is executed. Nil WaitSearcher and OpenSearcher use Solr's defaults (true).
+type CommitOptions struct {
+	// SoftCommit makes changes visible without flushing them to stable storage
+	SoftCommit bool `json:"softCommit,omitempty" url:"softCommit,omitempty"`
+	// WaitSearcher blocks until a new searcher is opened and registered
+	WaitSearcher *bool `json:"waitSearcher,omitempty" url:"waitSearcher,omitempty"`
+	// OpenSearcher opens a new searcher, making the changes visible to queries
+	OpenSearcher *bool `json:"openSearcher,omitempty" url:"openSearcher,omitempty"`
+	// ExpungeDeletes merges away segments with deleted documents
+	ExpungeDeletes bool `json:"expungeDeletes,omitempty" url:"expungeDeletes,omitempty"`
+	// Timeout of the commit request. Defaults to 30 seconds.
+	Timeout time.Duration `json:"-" url:"-"`
+}
+
+// Bool returns a pointer to b, for optional settings such as CommitOptions.WaitSearcher
+func Bool(b bool) *bool {
+	return &b
+}
+
+// Int returns a pointer to i, for optional settings such as ModifyCollectionOptions.ReplicationFactor
+func Int(i int) *int {
+	return &i
+}
 
+// CommitWithOptions executes a Solr commit command
+func (sc *SolrClient) CommitWithOptions(collectionName string, opts CommitOptions) error {
+	params, err := query.Values(opts)
+	if err != nil {
+		return err
+	}
+	params.Set("commit", "true")
+	return sc.updateCommand(collectionName, params, opts.Timeout, "commit")
+}
+
+// Optimize merges the index down to at most maxSegments segments. maxSegments <= 0 uses Solr's default of 1.
+func (sc *SolrClient) Optimize(collectionName string, maxSegments int) error {
+	return sc.OptimizeWithOptions(collectionName, OptimizeOptions{MaxSegments: maxSegments})
+}
+
+// OptimizeOptions control how an optimize is executed
+type OptimizeOptions struct {
+	// MaxSegments is the number of segments the index is merged down to. Zero uses Solr's default of 1.
+	MaxSegments int `url:"maxSegments,omitempty"`
+	// Timeout of the optimize request. Defaults to 30 seconds, which is too short for big indexes.
+	Timeout time.Duration `url:"-"`
+}
+
+// OptimizeWithOptions executes a Solr optimize command
+func (sc *SolrClient) OptimizeWithOptions(collectionName string, opts OptimizeOptions) error {
+	if opts.MaxSegments < 0 {
+		opts.MaxSegments = 0
+	}
+	params, err := query.Values(opts)
+	if err != nil {
+		return err
+	}
+	params.Set("optimize", "true")
+	return sc.updateCommand(collectionName, params, opts.Timeout, "optimize")
+}
+
+// Rollback discards all uncommitted changes. Rollback is not supported by SolrCloud, only standalone Solr.
+func (sc *SolrClient) Rollback(collectionName string) error {
 	var client = &http.Client{
 		Timeout: time.Second * 30,
 	}
+	_, err := sc.postUpdate(client, collectionName, nil, strings.NewReader(`{"rollback":{}}`))
+	if err != nil {
+		return fmt.Errorf("Error executing rollback command: %s", err)
+	}
+	return nil
+}
+
+// updateCommand executes a GET request against the /update handler, e.g. /update?commit=true
+func (sc *SolrClient) updateCommand(collectionName string, params url.Values, timeout time.Duration, name string) error {
+	if timeout <= 0 {
+		timeout = time.Second * 30
+	}
+
+	//http://localhost:8983/solr/techproducts/update?commit=true
+	url := "http://" + sc.LBNodeAddress() + "/" + collectionName + "/update?" + params.Encode()
+
+	var client = &http.Client{
+		Timeout: timeout,
+	}
 
 	resp, err := client.Get(url)
 	if err != nil {
-		return fmt.Errorf("Error executing commit command: %s", err.Error())
+		return fmt.Errorf("Error executing %s command: %s", name, err.Error())
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("Error executing commit command, status code = %d, full response:\n%s", resp.StatusCode, body)
+		return fmt.Errorf("Error executing %s command: %w", name, readSolrError(resp))
 	}
 
 	return nil
@@ -174,144 +287,214 @@ type solrDeleteCommand struct {
 }
 
 // DeleteByQuery deletes documents matching a Solr query
-func (sc *SolrClient) DeleteByQuery(collectionName string, query string) error {
-
-	url := "http://" + sc.LBNodeAddress() + "/" + collectionName + "/update"
+func (sc *SolrClient) DeleteByQuery(collectionName string, query string, opts ...UpdateOption) error {
+	return toleratedError(sc.DeleteByQueryWithResponse(collectionName, query, opts...))
+}
 
+// DeleteByQueryWithResponse deletes documents matching a Solr query and returns Solr's response
+func (sc *SolrClient) DeleteByQueryWithResponse(collectionName string, query string, opts ...UpdateOption) (*UpdateResponse, error) {
 	cmd := solrDeleteCommand{}
 	cmd.Delete.Query = query
 
 	jsn, err := json.Marshal(cmd)
 	if err != nil {
-		return fmt.Errorf("Error marshalling delete query to json: %s", err.Error())
+		return nil, fmt.Errorf("Error marshalling delete query to json: %s", err.Error())
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsn))
-	req.Header.Set("Content-Type", "application/json")
-
 	var client = &http.Client{
 		Timeout: time.Second * 10,
 	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return sc.postUpdate(client, collectionName, updateParams(opts), bytes.NewBuffer(jsn))
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("Error delete docs by query, status code = %d, full error:\n%s", resp.StatusCode, body)
-	}
-	return nil
+// PostStructs indexes a slice of structs. Struct fields are mapped to Solr fields using
+// `solr:"name,omitempty,multi"` tags, falling back to json tags (see MarshalSolrDoc)
+func (sc *SolrClient) PostStructs(data []interface{}, targetCollection string, opts ...UpdateOption) error {
+	return toleratedError(sc.PostStructsWithResponse(data, targetCollection, opts...))
 }
 
-func (sc *SolrClient) PostStructs(data []interface{}, targetCollection string) error {
-	url := "http://" + sc.LBNodeAddress() + "/" + targetCollection + "/update"
-	dataBytes, err := json.Marshal(data)
-	if err != nil {
-		return err
+// PostStructsWithResponse indexes a slice of structs and returns Solr's response
+func (sc *SolrClient) PostStructsWithResponse(data []interface{}, targetCollection string, opts ...UpdateOption) (*UpdateResponse, error) {
+	var client = &http.Client{
+		Timeout: time.Second * 10,
 	}
-	//fmt.Println(string(dataBytes))
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(dataBytes))
-	req.Header.Set("Content-Type", "application/json")
+	return sc.postStream(client, targetCollection, updateParams(opts), func(w io.Writer) error {
+		return writeStructsJSON(w, data)
+	})
+}
 
+// PostDocs indexes a SolrDocumentCollection. If Solr rejects a document because its
+// _version_ doesn't match, a *VersionConflictError is returned. Documents that failed
+// within MaxErrors are returned as a *TolerantUpdateError.
+func (sc *SolrClient) PostDocs(docs *SolrDocumentCollection, targetCollection string, opts ...UpdateOption) error {
+	return toleratedError(sc.PostDocsWithResponse(docs, targetCollection, opts...))
+}
+
+// PostDocsWithResponse indexes a SolrDocumentCollection and returns Solr's response.
+// Use ReturnVersions to get the _version_ assigned to each document.
+func (sc *SolrClient) PostDocsWithResponse(docs *SolrDocumentCollection, targetCollection string, opts ...UpdateOption) (*UpdateResponse, error) {
 	var client = &http.Client{
 		Timeout: time.Second * 10,
 	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	params := updateParams(opts)
+	if sc.usesJavabin() {
+		// the response is still read as JSON
+		params.Set("wt", "json")
+		return sc.postStreamHandler(client, targetCollection, "update", "application/javabin", params, docs.WriteJavabin)
 	}
-	defer resp.Body.Close()
+	return sc.postStream(client, targetCollection, params, docs.WriteJSON)
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("Error indexing docs, status code = %d, full error:\n%s", resp.StatusCode, body)
+// writeStructsJSON streams a slice of structs to w as a json array of Solr documents
+func writeStructsJSON(w io.Writer, data []interface{}) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	bw.WriteByte('[')
+	for i, item := range data {
+		doc, err := toSolrDoc(item)
+		if err != nil {
+			return fmt.Errorf("Error mapping struct at position %d: %s", i, err)
+		}
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
 	}
-	return nil
+	bw.WriteByte(']')
+	return bw.Flush()
+}
+
+// postStream streams an update request body produced by write, so large batches are never held in memory
+func (sc *SolrClient) postStream(client *http.Client, collection string, params url.Values, write func(w io.Writer) error) (*UpdateResponse, error) {
+	return sc.postStreamHandler(client, collection, "update", "application/json", params, write)
 }
 
-// PostDocs indexes a SolrDocumentCollection
-func (sc *SolrClient) PostDocs(docs *SolrDocumentCollection, targetCollection string) error {
-	url := "http://" + sc.LBNodeAddress() + "/" + targetCollection + "/update"
-	str, err := docs.SolrJSON()
+// postStreamHandler streams an update request body of any content type to an update handler
+func (sc *SolrClient) postStreamHandler(client *http.Client, collection string, handler string, contentType string, params url.Values, write func(w io.Writer) error) (*UpdateResponse, error) {
+	pr, pw := io.Pipe()
+	// closing the reader unblocks the writer if the request fails before the body is consumed
+	defer pr.Close()
+	go func() {
+		pw.CloseWithError(write(pw))
+	}()
+	return sc.postUpdateHandler(client, collection, handler, contentType, params, pr)
+}
+
+// postUpdate sends a JSON update request body to the /update handler of a collection
+func (sc *SolrClient) postUpdate(client *http.Client, collection string, params url.Values, body io.Reader) (*UpdateResponse, error) {
+	return sc.postUpdateHandler(client, collection, "update", "application/json", params, body)
+}
 
-	jsn := []byte("[" + str + "]")
+// postUpdateHandler sends an update request body of any content type to an update handler
+func (sc *SolrClient) postUpdateHandler(client *http.Client, collection string, handler string, contentType string, params url.Values, body io.Reader) (*UpdateResponse, error) {
+	node := sc.LBNodeAddress()
+	url := "http://" + node + "/" + collection + "/" + handler
+	if len(params) > 0 {
+		url += "?" + params.Encode()
+	}
+	req, err := http.NewRequest("POST", url, body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsn))
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 
-	var client = &http.Client{
-		Timeout: time.Second * 10,
-	}
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
 	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("Error indexing docs, status code = %d, full error:\n%s", resp.StatusCode, body)
+		return nil, newUpdateError(resp, buf)
 	}
-	return nil
+
+	updateResp, err := parseUpdateResponse(buf)
+	if err != nil {
+		return nil, err
+	}
+	updateResp.Node = node
+	updateResp.Elapsed = time.Since(start)
+	return updateResp, nil
+}
+
+// CreateCollectionOptions holds the parameters of a new collection. Zero values are left to Solr's defaults.
+type CreateCollectionOptions struct {
+	// NumShards is the number of shards of a collection using the compositeId router
+	NumShards int `url:"numShards,omitempty"`
+	// Shards names the shards of a collection using the implicit router
+	Shards            []string `url:"shards,comma,omitempty"`
+	ReplicationFactor int      `url:"replicationFactor,omitempty"`
+	NrtReplicas       int      `url:"nrtReplicas,omitempty"`
+	TlogReplicas      int      `url:"tlogReplicas,omitempty"`
+	PullReplicas      int      `url:"pullReplicas,omitempty"`
+	MaxShardsPerNode  int      `url:"maxShardsPerNode,omitempty"`
+	// ConfigName is the configset of the collection
+	ConfigName string `url:"collection.configName,omitempty"`
+	// RouterName is compositeId (the default) or implicit
+	RouterName string `url:"router.name,omitempty"`
+	// RouterField routes documents by this field instead of the unique key
+	RouterField string `url:"router.field,omitempty"`
+	// CreateNodeSet lists the nodes to create the replicas on. EMPTY creates the collection without replicas.
+	CreateNodeSet        []string `url:"createNodeSet,comma,omitempty"`
+	CreateNodeSetShuffle *bool    `url:"createNodeSet.shuffle,omitempty"`
+	// WaitForFinalState waits until all replicas are active before responding
+	WaitForFinalState bool `url:"waitForFinalState,omitempty"`
+	// Properties sets core properties of the replicas
+	Properties map[string]string `url:"-"`
+	// Timeout of the request. Defaults to 3 minutes, use CreateCollectionAsync for longer waits.
+	Timeout time.Duration `url:"-"`
 }
 
 // CreateCollection creates a Solr collection
 func (sc *SolrClient) CreateCollection(name string, numShards int, replicationFactor int, timeout time.Duration) error {
-	///admin/collections?action=CREATE&name=name
-	url := fmt.Sprintf("http://%s/admin/collections?action=CREATE&name=%s&numShards=%d&replicationFactor=%d", sc.LBNodeAddress(), name, numShards, replicationFactor)
-
-	var client = &http.Client{
-		Timeout: timeout,
-	}
+	_, err := sc.CreateCollectionWithOptions(name, CreateCollectionOptions{
+		NumShards:         numShards,
+		ReplicationFactor: replicationFactor,
+		Timeout:           timeout,
+	})
+	return err
+}
 
-	response, err := client.Get(url)
+// CreateCollectionWithOptions creates a Solr collection and returns the created cores. It returns a
+// *SolrCollectionExistsError if the collection already exists. If only some cores could be created, the
+// response is returned with the error, listing the created cores in Success and the failed nodes in Failure.
+func (sc *SolrClient) CreateCollectionWithOptions(name string, opts CreateCollectionOptions) (*CollectionsAPIResponse, error) {
+	params, err := adminParams(opts, opts.Properties)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	respCode := response.StatusCode
-	var collectionsAPIResp CollectionsAPIResponse
-	buf, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return err
+	params.Set("name", name)
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = collectionsAPITimeout
 	}
-	json.Unmarshal(buf, &collectionsAPIResp)
+	var resp CollectionsAPIResponse
+	err = sc.collectionsAPIWithTimeout("CREATE", params, timeout, &resp)
+	if err != nil && len(resp.Failure) == 0 {
+		return nil, collectionExistsError(name, err)
+	}
+	return partialResponse(&resp, err)
+}
 
-	if respCode == 200 {
-		//success
-		return nil
-	} else if respCode == 400 {
-		if strings.HasPrefix(collectionsAPIResp.Exception.Msg, "collection already exists") {
-			return &SolrCollectionExistsError{collectionsAPIResp.Exception.Msg}
-		}
-		return fmt.Errorf("Error in CreateCollection(): %s", collectionsAPIResp.Exception.Msg)
+// collectionExistsError converts Solr's error for an existing collection to a *SolrCollectionExistsError
+func collectionExistsError(name string, err error) error {
+	var se *SolrError
+	if errors.As(err, &se) && strings.HasPrefix(se.Msg, "collection already exists") {
+		return &SolrCollectionExistsError{collectionName: name, Err: se}
 	}
-	return nil
+	return err
 }
 
 // DeleteCollection deletes a Solr collection
 func (sc *SolrClient) DeleteCollection(name string) error {
-	///admin/collections?action=DELETE&name=collection
-	url := fmt.Sprintf("http://%s/admin/collections?action=DELETE&name=%s", sc.LBNodeAddress(), name)
-	var client = &http.Client{Timeout: time.Second * 10}
-	response, err := client.Get(url)
-	if err != nil {
-		return err
-	}
-	respCode := response.StatusCode
-	var collectionsAPIResp CollectionsAPIResponse
-	buf, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return err
-	}
-	json.Unmarshal(buf, &collectionsAPIResp)
-	if respCode == 200 {
-		return nil
-	} else if respCode == 400 {
-		return fmt.Errorf("Error in DeleteCollection(): %s", collectionsAPIResp.Exception.Msg)
-	}
-	return nil
+	return sc.collectionsAPI("DELETE", url.Values{"name": {name}}, nil)
 }
@@ -0,0 +1,551 @@
+package solrg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+// collectionsAPITimeout is the HTTP timeout of Collections API calls. Operations that can take longer should
+// be run asynchronously.
+const collectionsAPITimeout = time.Minute * 3
+
+// CollectionsResponse is the response of a Collections API operation without any other result
+type CollectionsResponse struct {
+	ResponseHeader struct {
+		Status int `json:"status"`
+		QTime  int `json:"QTime"`
+	} `json:"responseHeader"`
+}
+
+// CoreResult is the result of an operation on a single core, returned per node by some Collections API operations
+type CoreResult struct {
+	ResponseHeader struct {
+		Status int `json:"status"`
+		QTime  int `json:"QTime"`
+	} `json:"responseHeader"`
+	Core string `json:"core"`
+}
+
+// collectionsAPI runs a Collections API action and decodes the response into v. Solr reports some failures
+// with a 200 status and an exception or per node failures in the body, which are returned as a *SolrError too.
+// v is still decoded in that case, see partialResponse.
+func (sc *SolrClient) collectionsAPI(action string, params url.Values, v interface{}) error {
+	return sc.collectionsAPIWithTimeout(action, params, collectionsAPITimeout, v)
+}
+
+// collectionsAPIWithTimeout is collectionsAPI with a custom HTTP timeout
+func (sc *SolrClient) collectionsAPIWithTimeout(action string, params url.Values, timeout time.Duration, v interface{}) error {
+	resp, buf, err := sc.adminAPI("collections", action, params, nil, timeout)
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		if err := json.Unmarshal(buf, v); err != nil {
+			return fmt.Errorf("Error parsing %s response: %s", action, err)
+		}
+	}
+
+	var failed CollectionsAPIResponse
+	if json.Unmarshal(buf, &failed) == nil && (failed.Exception.Msg != "" || len(failed.Failure) > 0) {
+		se := newSolrError(resp, buf)
+		se.Msg = failed.Exception.Msg
+		se.Code = failed.Exception.RspCode
+		if se.Code > 0 {
+			se.StatusCode = se.Code
+		}
+		if failures := nodeFailures(failed.Failure); se.Msg == "" {
+			se.Msg = failures
+		} else if failures != "" {
+			se.Msg += "; " + failures
+		}
+		return se
+	}
+	return nil
+}
+
+// adminAPI sends a request to an /admin handler and returns the body of a 200 response, or a *SolrError.
+// A nil body sends a GET, anything else is POSTed as application/octet-stream.
+func (sc *SolrClient) adminAPI(handler string, action string, params url.Values, body io.Reader, timeout time.Duration) (*http.Response, []byte, error) {
+	return sc.adminAPIContext(context.Background(), handler, action, params, body, timeout)
+}
+
+// adminAPIContext is adminAPI with a context that cancels the request
+func (sc *SolrClient) adminAPIContext(ctx context.Context, handler string, action string, params url.Values, body io.Reader, timeout time.Duration) (*http.Response, []byte, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("action", action)
+	params.Set("wt", "json")
+	url := "http://" + sc.LBNodeAddress() + "/admin/" + handler + "?" + params.Encode()
+
+	method := "GET"
+	if body != nil {
+		method = "POST"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	var client = &http.Client{
+		Timeout: timeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error executing %s: %s", action, err)
+	}
+	defer resp.Body.Close()
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, nil, newSolrError(resp, buf)
+	}
+	return resp, buf, nil
+}
+
+// partialResponse returns the response of an operation that failed on some nodes along with the error, so
+// callers can tell which cores were created on the other nodes
+func partialResponse(resp *CollectionsAPIResponse, err error) (*CollectionsAPIResponse, error) {
+	if err != nil && len(resp.Failure) == 0 {
+		return nil, err
+	}
+	return resp, err
+}
+
+// nodeFailures formats the per node failures of a response, sorted by node
+func nodeFailures(failures map[string]string) string {
+	nodes := make([]string, 0, len(failures))
+	for node := range failures {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	msgs := make([]string, len(nodes))
+	for i, node := range nodes {
+		msgs[i] = node + ": " + failures[node]
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// adminParams encodes an options struct and adds property.* parameters
+func adminParams(opts interface{}, properties map[string]string) (url.Values, error) {
+	params, err := query.Values(opts)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range properties {
+		params.Set("property."+k, v)
+	}
+	return params, nil
+}
+
+// ListCollections returns the names of all collections
+func (sc *SolrClient) ListCollections() ([]string, error) {
+	var resp struct {
+		Collections []string `json:"collections"`
+	}
+	if err := sc.collectionsAPI("LIST", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Collections, nil
+}
+
+// ClusterStatusOptions limits the status returned by ClusterStatus
+type ClusterStatusOptions struct {
+	Collection string   `url:"collection,omitempty"`
+	Shards     []string `url:"shard,comma,omitempty"`
+	Route      string   `url:"_route_,omitempty"`
+}
+
+// ClusterStatusResponse describes the collections, aliases and live nodes of a cluster
+type ClusterStatusResponse struct {
+	CollectionsResponse
+	Cluster struct {
+		Collections map[string]CollectionState `json:"collections"`
+		Aliases     map[string]string          `json:"aliases"`
+		Roles       map[string][]string        `json:"roles"`
+		LiveNodes   []string                   `json:"live_nodes"`
+	} `json:"cluster"`
+}
+
+// CollectionState is the state of a collection. Solr returns some numbers as strings, which json.Number accepts.
+type CollectionState struct {
+	Shards            map[string]ShardState `json:"shards"`
+	ConfigName        string                `json:"configName"`
+	ReplicationFactor json.Number           `json:"replicationFactor"`
+	MaxShardsPerNode  json.Number           `json:"maxShardsPerNode"`
+	NrtReplicas       json.Number           `json:"nrtReplicas"`
+	TlogReplicas      json.Number           `json:"tlogReplicas"`
+	PullReplicas      json.Number           `json:"pullReplicas"`
+	Router            struct {
+		Name  string `json:"name"`
+		Field string `json:"field"`
+	} `json:"router"`
+	ZNodeVersion int      `json:"znodeVersion"`
+	Aliases      []string `json:"aliases"`
+	// Health is GREEN, YELLOW, ORANGE or RED, reported by Solr 8 and later
+	Health string `json:"health"`
+}
+
+// ShardState is the state of a shard
+type ShardState struct {
+	Range    string                  `json:"range"`
+	State    string                  `json:"state"`
+	Replicas map[string]ReplicaState `json:"replicas"`
+	Health   string                  `json:"health"`
+}
+
+// ReplicaState is the state of a replica
+type ReplicaState struct {
+	Core     string `json:"core"`
+	BaseURL  string `json:"base_url"`
+	NodeName string `json:"node_name"`
+	State    string `json:"state"`
+	// Type is NRT, TLOG or PULL
+	Type   string `json:"type"`
+	Leader string `json:"leader"`
+}
+
+// IsLeader reports whether the replica is the leader of its shard
+func (rs ReplicaState) IsLeader() bool {
+	return rs.Leader == "true"
+}
+
+// ClusterStatus returns the state of the cluster, optionally limited to a collection or shards
+func (sc *SolrClient) ClusterStatus(opts ClusterStatusOptions) (*ClusterStatusResponse, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp ClusterStatusResponse
+	if err := sc.collectionsAPI("CLUSTERSTATUS", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ReloadCollection reloads all cores of a collection, e.g. after a configuration change
+func (sc *SolrClient) ReloadCollection(name string) (*CollectionsResponse, error) {
+	var resp CollectionsResponse
+	if err := sc.collectionsAPI("RELOAD", url.Values{"name": {name}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ModifyCollectionOptions holds the attributes changed by ModifyCollection. Nil attributes are left unchanged.
+type ModifyCollectionOptions struct {
+	ReplicationFactor *int   `url:"replicationFactor,omitempty"`
+	MaxShardsPerNode  *int   `url:"maxShardsPerNode,omitempty"`
+	AutoAddReplicas   *bool  `url:"autoAddReplicas,omitempty"`
+	ConfigName        string `url:"collection.configName,omitempty"`
+	// Properties sets collection properties
+	Properties map[string]string `url:"-"`
+}
+
+// ModifyCollection changes attributes of a collection
+func (sc *SolrClient) ModifyCollection(name string, opts ModifyCollectionOptions) (*CollectionsResponse, error) {
+	params, err := adminParams(opts, opts.Properties)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("collection", name)
+	var resp CollectionsResponse
+	if err := sc.collectionsAPI("MODIFYCOLLECTION", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateAlias creates or replaces an alias pointing to one or more collections
+func (sc *SolrClient) CreateAlias(name string, collections ...string) (*CollectionsResponse, error) {
+	params := url.Values{"name": {name}, "collections": {strings.Join(collections, ",")}}
+	var resp CollectionsResponse
+	if err := sc.collectionsAPI("CREATEALIAS", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteAlias deletes an alias
+func (sc *SolrClient) DeleteAlias(name string) (*CollectionsResponse, error) {
+	var resp CollectionsResponse
+	if err := sc.collectionsAPI("DELETEALIAS", url.Values{"name": {name}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListAliasesResponse lists the aliases of a cluster
+type ListAliasesResponse struct {
+	CollectionsResponse
+	// Aliases maps alias names to the collections they point to
+	Aliases map[string][]string `json:"-"`
+	// Properties maps alias names to their properties
+	Properties map[string]map[string]string `json:"properties"`
+}
+
+// ListAliases returns all aliases and the collections they point to
+func (sc *SolrClient) ListAliases() (*ListAliasesResponse, error) {
+	var resp struct {
+		ListAliasesResponse
+		Aliases map[string]string `json:"aliases"`
+	}
+	if err := sc.collectionsAPI("LISTALIASES", nil, &resp); err != nil {
+		return nil, err
+	}
+	aliases := resp.ListAliasesResponse
+	aliases.Aliases = make(map[string][]string, len(resp.Aliases))
+	for name, collections := range resp.Aliases {
+		aliases.Aliases[name] = strings.Split(collections, ",")
+	}
+	return &aliases, nil
+}
+
+// AddReplicaOptions describes the replica added by AddReplica. Collection and either Shard or Route are required.
+type AddReplicaOptions struct {
+	Collection string `url:"collection"`
+	Shard      string `url:"shard,omitempty"`
+	Route      string `url:"_route_,omitempty"`
+	// Node is the node name, e.g. 192.168.1.1:8983_solr. Solr picks one if empty.
+	Node string `url:"node,omitempty"`
+	// Type is NRT, TLOG or PULL
+	Type        string `url:"type,omitempty"`
+	InstanceDir string `url:"instanceDir,omitempty"`
+	DataDir     string `url:"dataDir,omitempty"`
+	// Properties sets core properties of the new replica
+	Properties map[string]string `url:"-"`
+}
+
+// AddReplica adds a replica to a shard
+func (sc *SolrClient) AddReplica(opts AddReplicaOptions) (*CollectionsAPIResponse, error) {
+	params, err := adminParams(opts, opts.Properties)
+	if err != nil {
+		return nil, err
+	}
+	var resp CollectionsAPIResponse
+	return partialResponse(&resp, sc.collectionsAPI("ADDREPLICA", params, &resp))
+}
+
+// DeleteReplicaOptions selects the replicas deleted by DeleteReplica: either a Replica of a Shard, or Count
+// replicas of a Shard, picked by Solr.
+type DeleteReplicaOptions struct {
+	Collection        string `url:"collection"`
+	Shard             string `url:"shard,omitempty"`
+	Replica           string `url:"replica,omitempty"`
+	Count             int    `url:"count,omitempty"`
+	DeleteInstanceDir *bool  `url:"deleteInstanceDir,omitempty"`
+	DeleteDataDir     *bool  `url:"deleteDataDir,omitempty"`
+	DeleteIndex       *bool  `url:"deleteIndex,omitempty"`
+	OnlyIfDown        bool   `url:"onlyIfDown,omitempty"`
+}
+
+// DeleteReplica deletes replicas of a shard
+func (sc *SolrClient) DeleteReplica(opts DeleteReplicaOptions) (*CollectionsResponse, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp CollectionsResponse
+	if err := sc.collectionsAPI("DELETEREPLICA", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SplitShardOptions describes how SplitShard splits a shard. Set one of Shard or SplitKey.
+type SplitShardOptions struct {
+	Collection string `url:"collection"`
+	Shard      string `url:"shard,omitempty"`
+	// Ranges are hash ranges of the new shards, e.g. 0-1f4,1f5-3e8
+	Ranges   string `url:"ranges,omitempty"`
+	SplitKey string `url:"split.key,omitempty"`
+	// SplitMethod is rewrite (the default) or link
+	SplitMethod  string `url:"splitMethod,omitempty"`
+	NumSubShards int    `url:"numSubShards,omitempty"`
+	// Properties sets core properties of the new replicas
+	Properties map[string]string `url:"-"`
+}
+
+// SplitShard splits a shard into two or more sub shards. Large shards take longer than the HTTP timeout to
+// split, use the async variant for those.
+func (sc *SolrClient) SplitShard(opts SplitShardOptions) (*CollectionsAPIResponse, error) {
+	params, err := adminParams(opts, opts.Properties)
+	if err != nil {
+		return nil, err
+	}
+	var resp CollectionsAPIResponse
+	return partialResponse(&resp, sc.collectionsAPI("SPLITSHARD", params, &resp))
+}
+
+// CreateShardOptions describes the shard created by CreateShard, for collections using the implicit router
+type CreateShardOptions struct {
+	Collection string `url:"collection"`
+	Shard      string `url:"shard"`
+	// CreateNodeSet lists the nodes to create the replicas on
+	CreateNodeSet []string `url:"createNodeSet,comma,omitempty"`
+	// Properties sets core properties of the new replicas
+	Properties map[string]string `url:"-"`
+}
+
+// CreateShard adds a shard to a collection using the implicit router
+func (sc *SolrClient) CreateShard(opts CreateShardOptions) (*CollectionsAPIResponse, error) {
+	params, err := adminParams(opts, opts.Properties)
+	if err != nil {
+		return nil, err
+	}
+	var resp CollectionsAPIResponse
+	return partialResponse(&resp, sc.collectionsAPI("CREATESHARD", params, &resp))
+}
+
+// DeleteShardOptions selects the shard deleted by DeleteShard. Only inactive shards, or any shard of a
+// collection using the implicit router, can be deleted.
+type DeleteShardOptions struct {
+	Collection        string `url:"collection"`
+	Shard             string `url:"shard"`
+	DeleteInstanceDir *bool  `url:"deleteInstanceDir,omitempty"`
+	DeleteDataDir     *bool  `url:"deleteDataDir,omitempty"`
+	DeleteIndex       *bool  `url:"deleteIndex,omitempty"`
+}
+
+// DeleteShard deletes a shard
+func (sc *SolrClient) DeleteShard(opts DeleteShardOptions) (*CollectionsResponse, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp CollectionsResponse
+	if err := sc.collectionsAPI("DELETESHARD", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MigrateDocsOptions selects the documents MigrateDocs moves to another collection
+type MigrateDocsOptions struct {
+	Collection       string `url:"collection"`
+	TargetCollection string `url:"target.collection"`
+	// SplitKey is the routing key prefix of the documents to move, e.g. a!
+	SplitKey string `url:"split.key"`
+	// ForwardTimeout is how long updates for the moved documents are forwarded to the target collection
+	ForwardTimeout time.Duration `url:"-"`
+	// Properties sets core properties of the temporary cores
+	Properties map[string]string `url:"-"`
+}
+
+// MigrateDocs moves all documents with a routing key from one collection to another
+func (sc *SolrClient) MigrateDocs(opts MigrateDocsOptions) (*CollectionsResponse, error) {
+	params, err := migrateDocsParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	var resp CollectionsResponse
+	if err := sc.collectionsAPI("MIGRATE", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// migrateDocsParams encodes the parameters of MIGRATE
+func migrateDocsParams(opts MigrateDocsOptions) (url.Values, error) {
+	params, err := adminParams(opts, opts.Properties)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ForwardTimeout > 0 {
+		params.Set("forward.timeout", fmt.Sprint(int64(opts.ForwardTimeout/time.Second)))
+	}
+	return params, nil
+}
+
+// BalanceShardUniqueOptions selects the property BalanceShardUnique spreads across nodes
+type BalanceShardUniqueOptions struct {
+	Collection string `url:"collection"`
+	// Property is the replica property, e.g. preferredLeader
+	Property        string `url:"property"`
+	OnlyActiveNodes *bool  `url:"onlyactivenodes,omitempty"`
+	// ShardUnique is required for properties other than preferredLeader
+	ShardUnique bool `url:"shardUnique,omitempty"`
+}
+
+// BalanceShardUnique assigns a replica property to exactly one replica per shard, spread evenly across nodes
+func (sc *SolrClient) BalanceShardUnique(opts BalanceShardUniqueOptions) (*CollectionsResponse, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp CollectionsResponse
+	if err := sc.collectionsAPI("BALANCESHARDUNIQUE", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AddReplicaPropOptions describes the property AddReplicaProp sets on a replica
+type AddReplicaPropOptions struct {
+	Collection string `url:"collection"`
+	Shard      string `url:"shard"`
+	Replica    string `url:"replica"`
+	// Property is the name of the property, e.g. preferredLeader. Solr adds the property. prefix if missing.
+	Property      string `url:"property"`
+	PropertyValue string `url:"property.value"`
+	// ShardUnique removes the property from the other replicas of the shard
+	ShardUnique bool `url:"shardUnique,omitempty"`
+}
+
+// AddReplicaProp sets a property on a replica
+func (sc *SolrClient) AddReplicaProp(opts AddReplicaPropOptions) (*CollectionsResponse, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp CollectionsResponse
+	if err := sc.collectionsAPI("ADDREPLICAPROP", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RequestStatusResponse is the status of an asynchronous Collections API request
+type RequestStatusResponse struct {
+	// CollectionsAPIResponse holds the results of a completed or failed request
+	CollectionsAPIResponse
+	Status struct {
+		// State is submitted, running, completed, failed or notfound
+		State string `json:"state"`
+		Msg   string `json:"msg"`
+	} `json:"status"`
+}
+
+// RequestStatus returns the status of an asynchronous Collections API request. The response of a failed
+// request holds the exception it failed with, which is not returned as an error.
+func (sc *SolrClient) RequestStatus(requestID string) (*RequestStatusResponse, error) {
+	return sc.requestStatus(context.Background(), requestID)
+}
+
+// requestStatus is RequestStatus with a context that cancels the request
+func (sc *SolrClient) requestStatus(ctx context.Context, requestID string) (*RequestStatusResponse, error) {
+	_, buf, err := sc.adminAPIContext(ctx, "collections", "REQUESTSTATUS", url.Values{"requestid": {requestID}}, nil, collectionsAPITimeout)
+	if err != nil {
+		return nil, err
+	}
+	var resp RequestStatusResponse
+	if err := json.Unmarshal(buf, &resp); err != nil {
+		return nil, fmt.Errorf("Error parsing REQUESTSTATUS response: %s", err)
+	}
+	return &resp, nil
+}
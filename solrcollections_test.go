@@ -0,0 +1,193 @@
+package solrg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectionsAPI(t *testing.T) {
+	var lastQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/collections" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		lastQuery = r.URL.Query()
+		switch lastQuery.Get("action") {
+		case "LIST":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"collections":["a","b c"]}`)
+		case "CLUSTERSTATUS":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":3},"cluster":{"collections":{"a":{
+				"pullReplicas":"0","replicationFactor":"2","maxShardsPerNode":2,"znodeVersion":11,"configName":"_default",
+				"router":{"name":"compositeId"},"health":"GREEN","shards":{"shard1":{"range":"80000000-7fffffff","state":"active","health":"GREEN",
+				"replicas":{"core_node2":{"core":"a_shard1_replica_n1","base_url":"http://10.0.0.1:8983/solr","node_name":"10.0.0.1:8983_solr","state":"active","type":"NRT","leader":"true"}}}}}},
+				"aliases":{"both":"a,b c"},"live_nodes":["10.0.0.1:8983_solr"]}}`)
+		case "LISTALIASES":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":0},"aliases":{"both":"a,b c"},"properties":{"both":{"owner":"me"}}}`)
+		case "ADDREPLICA":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":900},"success":{"10.0.0.2:8983_solr":{"responseHeader":{"status":0,"QTime":800},"core":"a_shard1_replica_n3"}}}`)
+		case "CREATESHARD":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":700},"success":{"10.0.0.1:8983_solr":{"responseHeader":{"status":0,"QTime":600},"core":"a_shard3_replica_n1"}},
+				"failure":{"10.0.0.2:8983_solr":"org.apache.solr.client.solrj.SolrServerException:No space left on device"}}`)
+		case "SPLITSHARD":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":5},"exception":{"msg":"The shard1_0 sub shard already exists","rspCode":400}}`)
+		case "RELOAD":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"responseHeader":{"status":400,"QTime":1},"error":{"metadata":["error-class","org.apache.solr.common.SolrException"],"msg":"Could not find collection : missing","code":400}}`)
+		case "REQUESTSTATUS":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"completed","msg":"found [1000] in completed tasks"}}`)
+		default:
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":2}}`)
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	names, err := sc.ListCollections()
+	must(err)
+	if len(names) != 2 || names[1] != "b c" || lastQuery.Get("wt") != "json" {
+		t.Errorf("Unexpected collections %v for %v", names, lastQuery)
+	}
+
+	status, err := sc.ClusterStatus(ClusterStatusOptions{Collection: "a", Shards: []string{"shard1", "shard2"}})
+	must(err)
+	if lastQuery.Get("collection") != "a" || lastQuery.Get("shard") != "shard1,shard2" {
+		t.Errorf("Unexpected cluster status parameters %v", lastQuery)
+	}
+	coll := status.Cluster.Collections["a"]
+	replica := coll.Shards["shard1"].Replicas["core_node2"]
+	if coll.ReplicationFactor != "2" || coll.MaxShardsPerNode != "2" || coll.Router.Name != "compositeId" || !replica.IsLeader() || replica.Type != "NRT" {
+		t.Errorf("Unexpected cluster status %+v", status.Cluster)
+	}
+	if len(status.Cluster.LiveNodes) != 1 || status.Cluster.Aliases["both"] != "a,b c" {
+		t.Errorf("Unexpected live nodes or aliases %+v", status.Cluster)
+	}
+
+	_, err = sc.CreateAlias("both", "a", "b c")
+	must(err)
+	if lastQuery.Get("action") != "CREATEALIAS" || lastQuery.Get("collections") != "a,b c" {
+		t.Errorf("Unexpected alias parameters %v", lastQuery)
+	}
+	aliases, err := sc.ListAliases()
+	must(err)
+	if len(aliases.Aliases["both"]) != 2 || aliases.Aliases["both"][1] != "b c" || aliases.Properties["both"]["owner"] != "me" {
+		t.Errorf("Unexpected aliases %+v", aliases)
+	}
+
+	added, err := sc.AddReplica(AddReplicaOptions{Collection: "a", Shard: "shard1", Type: "TLOG", Properties: map[string]string{"preferredLeader": "true"}})
+	must(err)
+	if lastQuery.Get("type") != "TLOG" || lastQuery.Get("property.preferredLeader") != "true" || lastQuery.Get("node") != "" {
+		t.Errorf("Unexpected add replica parameters %v", lastQuery)
+	}
+	if added.Success["10.0.0.2:8983_solr"].Core != "a_shard1_replica_n3" {
+		t.Errorf("Unexpected add replica response %+v", added)
+	}
+
+	_, err = sc.ModifyCollection("a&b", ModifyCollectionOptions{ReplicationFactor: Int(3), AutoAddReplicas: Bool(false)})
+	must(err)
+	if lastQuery.Get("collection") != "a&b" || lastQuery.Get("replicationFactor") != "3" || lastQuery.Get("autoAddReplicas") != "false" || lastQuery.Get("maxShardsPerNode") != "" {
+		t.Errorf("Unexpected modify parameters %v", lastQuery)
+	}
+
+	_, err = sc.MigrateDocs(MigrateDocsOptions{Collection: "a", TargetCollection: "b", SplitKey: "tenant!", ForwardTimeout: time.Minute})
+	must(err)
+	if lastQuery.Get("target.collection") != "b" || lastQuery.Get("split.key") != "tenant!" || lastQuery.Get("forward.timeout") != "60" {
+		t.Errorf("Unexpected migrate parameters %v", lastQuery)
+	}
+
+	created, err := sc.CreateShard(CreateShardOptions{Collection: "a", Shard: "shard3"})
+	if err == nil || created == nil || created.Success["10.0.0.1:8983_solr"].Core != "a_shard3_replica_n1" {
+		t.Errorf("Expected the created core with the failure, got %+v: %v", created, err)
+	}
+
+	_, err = sc.SplitShard(SplitShardOptions{Collection: "a", Shard: "shard1"})
+	if !IsBadRequest(err) || !strings.Contains(err.Error(), "sub shard already exists") {
+		t.Errorf("Expected the exception as a bad request, got %v", err)
+	}
+	_, err = sc.ReloadCollection("missing")
+	if !IsBadRequest(err) || !strings.Contains(err.Error(), "Could not find collection") {
+		t.Errorf("Expected a bad request, got %v", err)
+	}
+
+	rs, err := sc.RequestStatus("1000")
+	must(err)
+	if rs.Status.State != "completed" || lastQuery.Get("requestid") != "1000" {
+		t.Errorf("Unexpected request status %+v", rs)
+	}
+}
+
+func TestCreateCollection(t *testing.T) {
+	var lastQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastQuery = r.URL.Query()
+		switch lastQuery.Get("name") {
+		case "new":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":2100},"success":{
+				"10.0.0.1:8983_solr":{"responseHeader":{"status":0,"QTime":1500},"core":"new_shard1_replica_n1"},
+				"10.0.0.2:8983_solr":{"responseHeader":{"status":0,"QTime":1600},"core":"new_shard2_replica_n2"}}}`)
+		case "exists":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"responseHeader":{"status":400,"QTime":3},"Operation create caused exception:":"org.apache.solr.common.SolrException:org.apache.solr.common.SolrException: collection already exists: exists","exception":{"msg":"collection already exists: exists","rspCode":400},"error":{"metadata":["error-class","org.apache.solr.common.SolrException"],"msg":"collection already exists: exists","code":400}}`)
+		case "partial":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":900},"success":{"10.0.0.1:8983_solr":{"responseHeader":{"status":0,"QTime":800},"core":"partial_shard1_replica_n1"}},
+				"failure":{"10.0.0.2:8983_solr":"org.apache.solr.client.solrj.impl.HttpSolrClient$RemoteSolrException:Error CREATEing SolrCore 'partial_shard2_replica_n2'"}}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"responseHeader":{"status":500,"QTime":1},"error":{"msg":"Could not fully create collection: broken","code":500}}`)
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	resp, err := sc.CreateCollectionWithOptions("new", CreateCollectionOptions{
+		NumShards:         2,
+		NrtReplicas:       1,
+		TlogReplicas:      1,
+		ConfigName:        "products",
+		RouterField:       "tenant",
+		CreateNodeSet:     []string{"10.0.0.1:8983_solr", "10.0.0.2:8983_solr"},
+		WaitForFinalState: true,
+		Properties:        map[string]string{"dataDir": "/data"},
+	})
+	must(err)
+	for k, v := range map[string]string{"action": "CREATE", "numShards": "2", "nrtReplicas": "1", "tlogReplicas": "1",
+		"collection.configName": "products", "router.field": "tenant", "createNodeSet": "10.0.0.1:8983_solr,10.0.0.2:8983_solr",
+		"waitForFinalState": "true", "property.dataDir": "/data", "replicationFactor": "", "pullReplicas": ""} {
+		if lastQuery.Get(k) != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, lastQuery.Get(k))
+		}
+	}
+	if len(resp.Success) != 2 || resp.Success["10.0.0.2:8983_solr"].Core != "new_shard2_replica_n2" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+
+	err = sc.CreateCollection("exists", 1, 2, time.Second*10)
+	if _, ok := err.(*SolrCollectionExistsError); !ok || !IsBadRequest(err) || err.Error() != "Collection exists already exists" {
+		t.Errorf("Expected a *SolrCollectionExistsError, got %T: %v", err, err)
+	}
+
+	err = sc.CreateCollection("partial", 2, 1, time.Second*10)
+	if err == nil || !strings.Contains(err.Error(), "10.0.0.2:8983_solr: org.apache.solr.client.solrj.impl.HttpSolrClient$RemoteSolrException:Error CREATEing SolrCore") {
+		t.Errorf("Expected the per node failure, got %v", err)
+	}
+	resp, err = sc.CreateCollectionWithOptions("partial", CreateCollectionOptions{NumShards: 2})
+	if err == nil || resp == nil || resp.Success["10.0.0.1:8983_solr"].Core != "partial_shard1_replica_n1" || len(resp.Failure) != 1 {
+		t.Errorf("Expected the created cores with the failure, got %+v: %v", resp, err)
+	}
+
+	err = sc.CreateCollection("broken", 1, 1, time.Second*10)
+	var se *SolrError
+	if !errors.As(err, &se) || se.StatusCode != 500 || !strings.Contains(err.Error(), "Could not fully create collection") {
+		t.Errorf("Expected a server error, got %v", err)
+	}
+}
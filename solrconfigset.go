@@ -0,0 +1,330 @@
+package solrg
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// configSetsAPITimeout is the HTTP timeout of ConfigSets API calls
+const configSetsAPITimeout = time.Minute
+
+// configSetsAPI sends a ConfigSets API request and decodes the response into v. A nil body sends a GET.
+func (sc *SolrClient) configSetsAPI(action string, params url.Values, body io.Reader, v interface{}) error {
+	_, buf, err := sc.adminAPI("configs", action, params, body, configSetsAPITimeout)
+	if err != nil || v == nil {
+		return err
+	}
+	if err := json.Unmarshal(buf, v); err != nil {
+		return fmt.Errorf("Error parsing configset %s response: %s", action, err)
+	}
+	return nil
+}
+
+// ListConfigSets returns the names of all configsets
+func (sc *SolrClient) ListConfigSets() ([]string, error) {
+	var resp struct {
+		ConfigSets []string `json:"configSets"`
+	}
+	if err := sc.configSetsAPI("LIST", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.ConfigSets, nil
+}
+
+// CreateConfigSet creates a configset as a copy of an existing one, e.g. _default. Properties are stored in
+// the new configset's configsetprops.json.
+func (sc *SolrClient) CreateConfigSet(name string, base string, properties map[string]string) error {
+	params := url.Values{"name": {name}}
+	if base != "" {
+		params.Set("baseConfigSet", base)
+	}
+	for k, v := range properties {
+		params.Set("configSetProp."+k, v)
+	}
+	return sc.configSetsAPI("CREATE", params, nil, nil)
+}
+
+// DeleteConfigSet deletes a configset. Solr refuses to delete configsets used by a collection.
+func (sc *SolrClient) DeleteConfigSet(name string) error {
+	return sc.configSetsAPI("DELETE", url.Values{"name": {name}}, nil, nil)
+}
+
+// UploadConfigSetOptions holds the optional parameters of UploadConfigSet
+type UploadConfigSetOptions struct {
+	// Overwrite replaces the files of an existing configset, requires Solr 8.7 or later
+	Overwrite bool `url:"overwrite,omitempty"`
+	// Cleanup deletes files of an existing configset that are not in the upload, used with Overwrite
+	Cleanup bool `url:"cleanup,omitempty"`
+}
+
+// UploadConfigSet uploads a zipped configset. The configuration files must be at the root of the zip file,
+// not in a conf directory.
+func (sc *SolrClient) UploadConfigSet(name string, zipFile io.Reader, opts UploadConfigSetOptions) error {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return err
+	}
+	params.Set("name", name)
+	return sc.configSetsAPI("UPLOAD", params, zipFile, nil)
+}
+
+// UploadConfigSetDir zips a configset directory, e.g. server/solr/configsets/_default/conf, and uploads it
+func (sc *SolrClient) UploadConfigSetDir(name string, dir string, opts UploadConfigSetOptions) error {
+	files, err := ReadConfigSetDir(dir)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, p := range sortedFileNames(files) {
+		w, err := zw.Create(p)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(files[p]); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return sc.UploadConfigSet(name, &buf, opts)
+}
+
+// ReadConfigSetDir reads the files of a configset directory, keyed by their slash separated path relative to dir
+func ReadConfigSetDir(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = b
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error reading configset directory %s: %s", dir, err)
+	}
+	return files, nil
+}
+
+// sortedFileNames returns the paths of a configset's files in order
+func sortedFileNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConfigSetDiff lists the files that differ between a local configset and the one in ZooKeeper, by path
+type ConfigSetDiff struct {
+	Added   []string
+	Changed []string
+	// Removed lists files only in ZooKeeper, which are deleted when pruning
+	Removed []string
+}
+
+// Empty reports whether the configsets are identical
+func (d *ConfigSetDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// String returns a readable summary of the diff, one file per line
+func (d *ConfigSetDiff) String() string {
+	if d.Empty() {
+		return "No configset changes"
+	}
+	var sb strings.Builder
+	for _, group := range []struct {
+		prefix string
+		paths  []string
+	}{{"+ ", d.Added}, {"~ ", d.Changed}, {"- ", d.Removed}} {
+		for _, p := range group.paths {
+			sb.WriteString(group.prefix + p + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// ZkUploadOptions controls ZkUploadConfigSet
+type ZkUploadOptions struct {
+	// DryRun computes the diff without writing to ZooKeeper
+	DryRun bool
+	// Prune deletes files that are in ZooKeeper but not in the local directory
+	Prune bool
+}
+
+// zkStore is the part of *zk.Conn used to read and write configsets
+type zkStore interface {
+	Children(path string) ([]string, *zk.Stat, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	Exists(path string) (bool, *zk.Stat, error)
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	Delete(path string, version int32) error
+}
+
+// ZkUploadConfigSet writes the files of a configset directory straight to /configs/<name> in ZooKeeper, like
+// solr zk upconfig, and returns the differences it found. Only added and changed files are written, so an
+// unchanged configset is left alone. Collections using the configset must be reloaded to pick up changes.
+// It requires a client connected to ZooKeeper with NewSolrClient.
+func (sc *SolrClient) ZkUploadConfigSet(name string, dir string, opts ZkUploadOptions) (*ConfigSetDiff, error) {
+	if sc.Connection == nil {
+		return nil, fmt.Errorf("ZkUploadConfigSet requires a ZooKeeper connection")
+	}
+	files, err := ReadConfigSetDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return zkUploadConfigSet(sc.Connection, name, files, opts)
+}
+
+// zkUploadConfigSet diffs and writes a configset, see ZkUploadConfigSet
+func zkUploadConfigSet(store zkStore, name string, files map[string][]byte, opts ZkUploadOptions) (*ConfigSetDiff, error) {
+	root := "/configs/" + name
+	current, err := zkReadConfigSet(store, root)
+	if err != nil {
+		return nil, err
+	}
+	// An empty directory in ZooKeeper reads as an empty file, it is only one if it isn't a local directory
+	dirs := configSetDirs(files)
+	for p, data := range current {
+		if len(data) == 0 && dirs[p] {
+			delete(current, p)
+		}
+	}
+
+	diff := &ConfigSetDiff{}
+	for _, p := range sortedFileNames(files) {
+		data, exists := current[p]
+		if !exists {
+			diff.Added = append(diff.Added, p)
+		} else if !bytes.Equal(data, files[p]) {
+			diff.Changed = append(diff.Changed, p)
+		}
+	}
+	for _, p := range sortedFileNames(current) {
+		if _, exists := files[p]; !exists {
+			diff.Removed = append(diff.Removed, p)
+		}
+	}
+	if opts.DryRun {
+		return diff, nil
+	}
+
+	for _, p := range diff.Added {
+		if err := zkCreateAll(store, root+"/"+p, files[p]); err != nil {
+			return diff, err
+		}
+	}
+	for _, p := range diff.Changed {
+		if _, err := store.Set(root+"/"+p, files[p], -1); err != nil {
+			return diff, fmt.Errorf("Error writing %s to ZooKeeper: %s", p, err)
+		}
+	}
+	if opts.Prune {
+		for _, p := range diff.Removed {
+			if err := zkDeleteFile(store, root, p); err != nil {
+				return diff, err
+			}
+		}
+	}
+	return diff, nil
+}
+
+// configSetDirs returns the directories of a configset's files, keyed by path relative to the configset
+func configSetDirs(files map[string][]byte) map[string]bool {
+	dirs := make(map[string]bool)
+	for p := range files {
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	return dirs
+}
+
+// zkReadConfigSet reads the files under a configset node, keyed by path relative to it. Nodes with children
+// are directories, childless nodes are files even when they have no data. A missing configset has no files.
+func zkReadConfigSet(store zkStore, root string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	exists, _, err := store.Exists(root)
+	if err != nil || !exists {
+		return files, err
+	}
+	var walk func(rel string) error
+	walk = func(rel string) error {
+		children, _, err := store.Children(path.Join(root, rel))
+		if err != nil {
+			return fmt.Errorf("Error listing %s in ZooKeeper: %s", path.Join(root, rel), err)
+		}
+		if len(children) == 0 && rel != "" {
+			data, _, err := store.Get(path.Join(root, rel))
+			if err != nil {
+				return fmt.Errorf("Error reading %s from ZooKeeper: %s", path.Join(root, rel), err)
+			}
+			files[rel] = data
+			return nil
+		}
+		for _, c := range children {
+			if err := walk(path.Join(rel, c)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return files, walk("")
+}
+
+// zkCreateAll creates a node with its missing parents
+func zkCreateAll(store zkStore, p string, data []byte) error {
+	parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		parent := "/" + strings.Join(parts[:i], "/")
+		if _, err := store.Create(parent, nil, 0, zk.WorldACL(zk.PermAll)); err != nil && err != zk.ErrNodeExists {
+			return fmt.Errorf("Error creating %s in ZooKeeper: %s", parent, err)
+		}
+	}
+	if _, err := store.Create(p, data, 0, zk.WorldACL(zk.PermAll)); err != nil {
+		return fmt.Errorf("Error creating %s in ZooKeeper: %s", p, err)
+	}
+	return nil
+}
+
+// zkDeleteFile deletes a file of a configset and the directories it leaves empty
+func zkDeleteFile(store zkStore, root string, rel string) error {
+	if err := store.Delete(root+"/"+rel, -1); err != nil && err != zk.ErrNoNode {
+		return fmt.Errorf("Error deleting %s from ZooKeeper: %s", rel, err)
+	}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		children, _, err := store.Children(root + "/" + dir)
+		if err != nil || len(children) > 0 {
+			return err
+		}
+		if err := store.Delete(root+"/"+dir, -1); err != nil && err != zk.ErrNoNode {
+			return fmt.Errorf("Error deleting %s from ZooKeeper: %s", dir, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package solrg
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+func TestConfigSetsAPI(t *testing.T) {
+	var lastQuery url.Values
+	var uploaded []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/configs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		lastQuery = r.URL.Query()
+		switch lastQuery.Get("action") {
+		case "LIST":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":2},"configSets":["_default","products"]}`)
+		case "UPLOAD":
+			if r.Method != "POST" || r.Header.Get("Content-Type") != "application/octet-stream" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			uploaded, _ = ioutil.ReadAll(r.Body)
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":40}}`)
+		case "DELETE":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"responseHeader":{"status":400,"QTime":1},"error":{"msg":"Can not delete ConfigSet as it is currently being used by collection [products]","code":400}}`)
+		default:
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":5}}`)
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	names, err := sc.ListConfigSets()
+	must(err)
+	if !reflect.DeepEqual(names, []string{"_default", "products"}) {
+		t.Errorf("Unexpected configsets %v", names)
+	}
+
+	must(sc.CreateConfigSet("products_v2", "products", map[string]string{"immutable": "false"}))
+	if lastQuery.Get("action") != "CREATE" || lastQuery.Get("baseConfigSet") != "products" || lastQuery.Get("configSetProp.immutable") != "false" {
+		t.Errorf("Unexpected create parameters %v", lastQuery)
+	}
+
+	dir := t.TempDir()
+	must(os.MkdirAll(filepath.Join(dir, "lang"), 0755))
+	must(ioutil.WriteFile(filepath.Join(dir, "solrconfig.xml"), []byte("<config/>"), 0644))
+	must(ioutil.WriteFile(filepath.Join(dir, "lang", "stopwords_en.txt"), []byte("a\nthe\n"), 0644))
+	must(sc.UploadConfigSetDir("products v3", dir, UploadConfigSetOptions{Overwrite: true}))
+	if lastQuery.Get("name") != "products v3" || lastQuery.Get("overwrite") != "true" || lastQuery.Get("cleanup") != "" {
+		t.Errorf("Unexpected upload parameters %v", lastQuery)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(uploaded), int64(len(uploaded)))
+	must(err)
+	var entries []string
+	for _, f := range zr.File {
+		entries = append(entries, f.Name)
+	}
+	if !reflect.DeepEqual(entries, []string{"lang/stopwords_en.txt", "solrconfig.xml"}) {
+		t.Errorf("Unexpected zip entries %v", entries)
+	}
+
+	err = sc.DeleteConfigSet("products")
+	if !IsBadRequest(err) || !strings.Contains(err.Error(), "currently being used") {
+		t.Errorf("Expected a bad request, got %v", err)
+	}
+}
+
+// memZk is an in memory zkStore
+type memZk map[string][]byte
+
+func (m memZk) Children(p string) ([]string, *zk.Stat, error) {
+	if _, ok := m[p]; !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	var children []string
+	for k := range m {
+		if path.Dir(k) == p && k != "/" {
+			children = append(children, path.Base(k))
+		}
+	}
+	sort.Strings(children)
+	return children, &zk.Stat{}, nil
+}
+
+func (m memZk) Get(p string) ([]byte, *zk.Stat, error) {
+	data, ok := m[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	return data, &zk.Stat{}, nil
+}
+
+func (m memZk) Exists(p string) (bool, *zk.Stat, error) {
+	_, ok := m[p]
+	return ok, &zk.Stat{}, nil
+}
+
+func (m memZk) Create(p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	if _, ok := m[p]; ok {
+		return "", zk.ErrNodeExists
+	}
+	if _, ok := m[path.Dir(p)]; !ok {
+		return "", zk.ErrNoNode
+	}
+	m[p] = data
+	return p, nil
+}
+
+func (m memZk) Set(p string, data []byte, version int32) (*zk.Stat, error) {
+	if _, ok := m[p]; !ok {
+		return nil, zk.ErrNoNode
+	}
+	m[p] = data
+	return &zk.Stat{}, nil
+}
+
+func (m memZk) Delete(p string, version int32) error {
+	if _, ok := m[p]; !ok {
+		return zk.ErrNoNode
+	}
+	if children, _, _ := m.Children(p); len(children) > 0 {
+		return zk.ErrNotEmpty
+	}
+	delete(m, p)
+	return nil
+}
+
+func TestZkUploadConfigSet(t *testing.T) {
+	store := memZk{
+		"/":                                    nil,
+		"/configs":                             nil,
+		"/configs/products":                    nil,
+		"/configs/products/solrconfig.xml":     []byte("<config/>"),
+		"/configs/products/managed-schema":     []byte("<schema version='1'/>"),
+		"/configs/products/old":                nil,
+		"/configs/products/old/synonyms.txt":   []byte("tv,television"),
+		"/configs/products/lang":               nil,
+		"/configs/products/lang/stopwords.txt": []byte("a"),
+	}
+	files := map[string][]byte{
+		"solrconfig.xml":         []byte("<config/>"),
+		"managed-schema":         []byte("<schema version='2'/>"),
+		"lang/stopwords.txt":     []byte("a"),
+		"lang/stopwords_de.txt":  []byte("der"),
+		"velocity/browse.vm":     []byte("#parse('layout.vm')"),
+		"velocity/hit/simple.vm": []byte(""),
+	}
+
+	diff, err := zkUploadConfigSet(store, "products", files, ZkUploadOptions{DryRun: true})
+	must(err)
+	expected := &ConfigSetDiff{
+		Added:   []string{"lang/stopwords_de.txt", "velocity/browse.vm", "velocity/hit/simple.vm"},
+		Changed: []string{"managed-schema"},
+		Removed: []string{"old/synonyms.txt"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("Unexpected diff %+v", diff)
+	}
+	if string(store["/configs/products/managed-schema"]) != "<schema version='1'/>" || len(store) != 9 {
+		t.Errorf("Dry run changed ZooKeeper")
+	}
+	if !strings.HasPrefix(diff.String(), "+ lang/stopwords_de.txt\n") || !strings.HasSuffix(diff.String(), "~ managed-schema\n- old/synonyms.txt\n") {
+		t.Errorf("Unexpected diff summary:\n%s", diff)
+	}
+
+	_, err = zkUploadConfigSet(store, "products", files, ZkUploadOptions{})
+	must(err)
+	if string(store["/configs/products/managed-schema"]) != "<schema version='2'/>" || string(store["/configs/products/velocity/browse.vm"]) != "#parse('layout.vm')" {
+		t.Errorf("Files were not written: %v", store)
+	}
+	if _, ok := store["/configs/products/old/synonyms.txt"]; !ok {
+		t.Errorf("Removed file deleted without pruning")
+	}
+
+	diff, err = zkUploadConfigSet(store, "products", files, ZkUploadOptions{Prune: true})
+	must(err)
+	if len(diff.Added) != 0 || len(diff.Changed) != 0 || len(diff.Removed) != 1 {
+		t.Errorf("Unexpected diff %+v", diff)
+	}
+	if _, ok := store["/configs/products/old"]; ok {
+		t.Errorf("Empty directory left after pruning")
+	}
+	diff, err = zkUploadConfigSet(store, "products", files, ZkUploadOptions{Prune: true})
+	must(err)
+	if !diff.Empty() || diff.String() != "No configset changes" {
+		t.Errorf("Expected no differences, got %+v", diff)
+	}
+
+	diff, err = zkUploadConfigSet(store, "new", map[string][]byte{"conf/solrconfig.xml": []byte("<config/>")}, ZkUploadOptions{})
+	must(err)
+	if len(diff.Added) != 1 || string(store["/configs/new/conf/solrconfig.xml"]) != "<config/>" {
+		t.Errorf("New configset not written: %+v", diff)
+	}
+
+	// empty directories left in ZooKeeper, e.g. by a previous prune, aren't files
+	must(store.Delete("/configs/products/velocity/hit/simple.vm", -1))
+	store["/configs/products/empty.txt"] = nil
+	diff, err = zkUploadConfigSet(store, "products", files, ZkUploadOptions{Prune: true})
+	must(err)
+	if len(diff.Added) != 1 || diff.Added[0] != "velocity/hit/simple.vm" || len(diff.Removed) != 1 || diff.Removed[0] != "empty.txt" {
+		t.Errorf("Unexpected diff %+v", diff)
+	}
+	if _, ok := store["/configs/products/velocity/hit/simple.vm"]; !ok {
+		t.Errorf("File in empty directory not written: %v", store)
+	}
+}
@@ -0,0 +1,224 @@
+package solrg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// coreAdminTimeout is the HTTP timeout of Core Admin API calls
+const coreAdminTimeout = time.Minute * 3
+
+// coreAdminAPI runs a Core Admin API action and decodes the response into v
+func (sc *SolrClient) coreAdminAPI(action string, params url.Values, v interface{}) error {
+	_, buf, err := sc.adminAPI("cores", action, params, nil, coreAdminTimeout)
+	if err != nil || v == nil {
+		return err
+	}
+	if err := json.Unmarshal(buf, v); err != nil {
+		return fmt.Errorf("Error parsing core %s response: %s", action, err)
+	}
+	return nil
+}
+
+// CoreAdminResponse is the response of a Core Admin API operation
+type CoreAdminResponse struct {
+	ResponseHeader struct {
+		Status int `json:"status"`
+		QTime  int `json:"QTime"`
+	} `json:"responseHeader"`
+	// Core is the name of the core, set by CreateCore
+	Core string `json:"core"`
+}
+
+// CoreStatusOptions limits the status returned by CoreStatus
+type CoreStatusOptions struct {
+	// Core limits the status to one core
+	Core string `url:"core,omitempty"`
+	// IndexInfo includes the index statistics, which is the default. Set it to false to speed up the call.
+	IndexInfo *bool `url:"indexInfo,omitempty"`
+}
+
+// CoreStatusResponse holds the status of cores by name
+type CoreStatusResponse struct {
+	CoreAdminResponse
+	Status map[string]CoreStatus `json:"status"`
+	// InitFailures holds the errors of cores that failed to load
+	InitFailures map[string]string `json:"initFailures"`
+}
+
+// CoreStatus is the status of a core. The status of a core that doesn't exist is empty.
+type CoreStatus struct {
+	Name        string    `json:"name"`
+	InstanceDir string    `json:"instanceDir"`
+	DataDir     string    `json:"dataDir"`
+	Config      string    `json:"config"`
+	Schema      string    `json:"schema"`
+	StartTime   time.Time `json:"startTime"`
+	// UptimeMillis is the time since the core was loaded in milliseconds
+	UptimeMillis int64           `json:"uptime"`
+	Index        CoreIndexStatus `json:"index"`
+}
+
+// Uptime returns the time since the core was loaded
+func (cs CoreStatus) Uptime() time.Duration {
+	return time.Duration(cs.UptimeMillis) * time.Millisecond
+}
+
+// CoreIndexStatus holds the statistics of a core's index
+type CoreIndexStatus struct {
+	NumDocs      int    `json:"numDocs"`
+	MaxDoc       int    `json:"maxDoc"`
+	DeletedDocs  int    `json:"deletedDocs"`
+	Version      int64  `json:"version"`
+	SegmentCount int    `json:"segmentCount"`
+	Current      bool   `json:"current"`
+	HasDeletions bool   `json:"hasDeletions"`
+	Directory    string `json:"directory"`
+	// LastModified is the time of the last commit, zero for an empty index
+	LastModified time.Time `json:"lastModified"`
+	SizeInBytes  int64     `json:"sizeInBytes"`
+	// Size is the readable index size, e.g. 1.2 MB
+	Size string `json:"size"`
+	// UserData holds the commit metadata, e.g. commitTimeMSec
+	UserData map[string]string `json:"userData"`
+}
+
+// CoreStatus returns the status of all cores, or of the core in opts
+func (sc *SolrClient) CoreStatus(opts CoreStatusOptions) (*CoreStatusResponse, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp CoreStatusResponse
+	if err := sc.coreAdminAPI("STATUS", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateCoreOptions holds the parameters of a new core. The instance directory must already hold the
+// configuration, unless ConfigSet names a configset of the node.
+type CreateCoreOptions struct {
+	// InstanceDir defaults to the core name
+	InstanceDir string `url:"instanceDir,omitempty"`
+	ConfigSet   string `url:"configSet,omitempty"`
+	// Config and Schema are file names relative to the conf directory
+	Config  string `url:"config,omitempty"`
+	Schema  string `url:"schema,omitempty"`
+	DataDir string `url:"dataDir,omitempty"`
+	// Properties sets core properties
+	Properties map[string]string `url:"-"`
+}
+
+// CreateCore creates and loads a core
+func (sc *SolrClient) CreateCore(name string, opts CreateCoreOptions) (*CoreAdminResponse, error) {
+	params, err := adminParams(opts, opts.Properties)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("name", name)
+	var resp CoreAdminResponse
+	if err := sc.coreAdminAPI("CREATE", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ReloadCore reloads a core, e.g. after a configuration change
+func (sc *SolrClient) ReloadCore(name string) (*CoreAdminResponse, error) {
+	var resp CoreAdminResponse
+	if err := sc.coreAdminAPI("RELOAD", url.Values{"core": {name}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RenameCore renames a core
+func (sc *SolrClient) RenameCore(name string, newName string) (*CoreAdminResponse, error) {
+	var resp CoreAdminResponse
+	if err := sc.coreAdminAPI("RENAME", url.Values{"core": {name}, "other": {newName}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SwapCores swaps the names of two cores, e.g. to put a rebuilt index live
+func (sc *SolrClient) SwapCores(name string, other string) (*CoreAdminResponse, error) {
+	var resp CoreAdminResponse
+	if err := sc.coreAdminAPI("SWAP", url.Values{"core": {name}, "other": {other}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UnloadCoreOptions selects what UnloadCore deletes besides unloading the core
+type UnloadCoreOptions struct {
+	DeleteIndex       bool `url:"deleteIndex,omitempty"`
+	DeleteDataDir     bool `url:"deleteDataDir,omitempty"`
+	DeleteInstanceDir bool `url:"deleteInstanceDir,omitempty"`
+}
+
+// UnloadCore unloads a core, which stops serving requests once the pending ones are done
+func (sc *SolrClient) UnloadCore(name string, opts UnloadCoreOptions) (*CoreAdminResponse, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("core", name)
+	var resp CoreAdminResponse
+	if err := sc.coreAdminAPI("UNLOAD", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MergeIndexesOptions lists the indexes MergeIndexes merges. Set IndexDirs or SrcCores.
+type MergeIndexesOptions struct {
+	// IndexDirs are index directories on the node, which must not be written to while merging
+	IndexDirs []string `url:"indexDir,omitempty"`
+	// SrcCores are cores on the node, whose indexes must not be written to while merging
+	SrcCores []string `url:"srcCore,omitempty"`
+}
+
+// MergeIndexes merges indexes into a core. The merged documents are visible after the next commit on it.
+func (sc *SolrClient) MergeIndexes(core string, opts MergeIndexesOptions) (*CoreAdminResponse, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("core", core)
+	var resp CoreAdminResponse
+	if err := sc.coreAdminAPI("MERGEINDEXES", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SplitCoreOptions describes how SplitCore splits an index. Set Paths or TargetCores as the destinations.
+type SplitCoreOptions struct {
+	// Paths are index directories written by the split
+	Paths []string `url:"path,omitempty"`
+	// TargetCores are existing cores the split documents are added to
+	TargetCores []string `url:"targetCore,omitempty"`
+	// Ranges are hash ranges of the destinations, e.g. 0-1f4,1f5-3e8. The index is split evenly without them.
+	Ranges   string `url:"ranges,omitempty"`
+	SplitKey string `url:"split.key,omitempty"`
+	// SplitMethod is rewrite (the default) or link
+	SplitMethod string `url:"splitMethod,omitempty"`
+}
+
+// SplitCore splits the index of a core into two or more indexes
+func (sc *SolrClient) SplitCore(core string, opts SplitCoreOptions) (*CoreAdminResponse, error) {
+	params, err := adminParams(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("core", core)
+	var resp CoreAdminResponse
+	if err := sc.coreAdminAPI("SPLIT", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
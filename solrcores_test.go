@@ -0,0 +1,111 @@
+package solrg
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoreAdmin(t *testing.T) {
+	var lastQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/cores" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		lastQuery = r.URL.Query()
+		switch lastQuery.Get("action") {
+		case "STATUS":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":4},"initFailures":{"broken":"org.apache.solr.common.SolrException:Could not load conf for core broken"},
+				"status":{"products":{"name":"products","instanceDir":"/var/solr/data/products","dataDir":"/var/solr/data/products/data/",
+				"config":"solrconfig.xml","schema":"managed-schema","startTime":"2023-11-02T09:15:30.123Z","uptime":3723000,
+				"index":{"numDocs":1200,"maxDoc":1250,"deletedDocs":50,"indexHeapUsageBytes":-1,"version":87,"segmentCount":4,"current":true,"hasDeletions":true,
+				"directory":"org.apache.lucene.store.NRTCachingDirectory:NRTCachingDirectory(MMapDirectory@/var/solr/data/products/data/index)",
+				"segmentsFile":"segments_9","segmentsFileSizeInBytes":412,"userData":{"commitTimeMSec":"1698916530000","commitCommandVer":"0"},
+				"lastModified":"2023-11-02T09:15:30.000Z","sizeInBytes":1258291,"size":"1.2 MB"}},
+				"empty":{"name":"empty","instanceDir":"/var/solr/data/empty","startTime":"2023-11-02T09:20:00.000Z","uptime":10,
+				"index":{"numDocs":0,"maxDoc":0,"deletedDocs":0,"version":2,"segmentCount":0,"current":true,"hasDeletions":false,"sizeInBytes":69,"size":"69 bytes"}}}}`)
+		case "CREATE":
+			if lastQuery.Get("name") == "exists" {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, `{"responseHeader":{"status":500,"QTime":1},"error":{"metadata":["error-class","org.apache.solr.common.SolrException"],"msg":"Core with name 'exists' already exists.","code":500}}`)
+				return
+			}
+			fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":700},"core":%q}`, lastQuery.Get("name"))
+		default:
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":3}}`)
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	status, err := sc.CoreStatus(CoreStatusOptions{})
+	must(err)
+	if lastQuery.Get("core") != "" || lastQuery.Get("indexInfo") != "" {
+		t.Errorf("Unexpected status parameters %v", lastQuery)
+	}
+	products := status.Status["products"]
+	idx := products.Index
+	if products.Uptime() != time.Hour+2*time.Minute+3*time.Second || !products.StartTime.Equal(time.Date(2023, 11, 2, 9, 15, 30, 123e6, time.UTC)) {
+		t.Errorf("Unexpected uptime or start time %+v", products)
+	}
+	if idx.NumDocs != 1200 || idx.DeletedDocs != 50 || idx.SizeInBytes != 1258291 || idx.Size != "1.2 MB" || idx.UserData["commitTimeMSec"] != "1698916530000" {
+		t.Errorf("Unexpected index status %+v", idx)
+	}
+	if !idx.LastModified.Equal(time.Date(2023, 11, 2, 9, 15, 30, 0, time.UTC)) || !status.Status["empty"].Index.LastModified.IsZero() {
+		t.Errorf("Unexpected last modified times %+v", status.Status)
+	}
+	if !strings.Contains(status.InitFailures["broken"], "Could not load conf") {
+		t.Errorf("Unexpected init failures %v", status.InitFailures)
+	}
+	_, err = sc.CoreStatus(CoreStatusOptions{Core: "products", IndexInfo: Bool(false)})
+	must(err)
+	if lastQuery.Get("core") != "products" || lastQuery.Get("indexInfo") != "false" {
+		t.Errorf("Unexpected status parameters %v", lastQuery)
+	}
+
+	created, err := sc.CreateCore("products_v2", CreateCoreOptions{ConfigSet: "products", Properties: map[string]string{"solr.autoCommit.maxTime": "15000"}})
+	must(err)
+	if created.Core != "products_v2" || lastQuery.Get("configSet") != "products" || lastQuery.Get("property.solr.autoCommit.maxTime") != "15000" || lastQuery.Get("instanceDir") != "" {
+		t.Errorf("Unexpected create %+v for %v", created, lastQuery)
+	}
+	_, err = sc.CreateCore("exists", CreateCoreOptions{})
+	if se, ok := err.(*SolrError); !ok || se.StatusCode != 500 || !strings.Contains(se.Msg, "already exists") {
+		t.Errorf("Expected a *SolrError, got %v", err)
+	}
+
+	for _, c := range []struct {
+		call     func() (*CoreAdminResponse, error)
+		expected url.Values
+	}{
+		{func() (*CoreAdminResponse, error) { return sc.ReloadCore("products") },
+			url.Values{"action": {"RELOAD"}, "core": {"products"}}},
+		{func() (*CoreAdminResponse, error) { return sc.RenameCore("products_v2", "products_next") },
+			url.Values{"action": {"RENAME"}, "core": {"products_v2"}, "other": {"products_next"}}},
+		{func() (*CoreAdminResponse, error) { return sc.SwapCores("products", "products_next") },
+			url.Values{"action": {"SWAP"}, "core": {"products"}, "other": {"products_next"}}},
+		{func() (*CoreAdminResponse, error) {
+			return sc.UnloadCore("products_next", UnloadCoreOptions{DeleteIndex: true, DeleteInstanceDir: true})
+		}, url.Values{"action": {"UNLOAD"}, "core": {"products_next"}, "deleteIndex": {"true"}, "deleteInstanceDir": {"true"}}},
+		{func() (*CoreAdminResponse, error) {
+			return sc.MergeIndexes("products", MergeIndexesOptions{SrcCores: []string{"a", "b"}})
+		}, url.Values{"action": {"MERGEINDEXES"}, "core": {"products"}, "srcCore": {"a", "b"}}},
+		{func() (*CoreAdminResponse, error) {
+			return sc.SplitCore("products", SplitCoreOptions{TargetCores: []string{"p1", "p2"}, SplitKey: "A!"})
+		}, url.Values{"action": {"SPLIT"}, "core": {"products"}, "targetCore": {"p1", "p2"}, "split.key": {"A!"}}},
+	} {
+		_, err := c.call()
+		must(err)
+		c.expected.Set("wt", "json")
+		if !reflect.DeepEqual(lastQuery, c.expected) {
+			t.Errorf("Expected parameters %v, got %v", c.expected, lastQuery)
+		}
+	}
+}
@@ -3,19 +3,24 @@ package solrg
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strconv"
+	"time"
 )
 
 // NewSolrDocument creates a new instance of a SolrDocument
 func NewSolrDocument(id string) SolrDocument {
 	sd := SolrDocument{}
-	sd.fields = make(map[string][]string)
-	sd.fields["id"] = []string{id}
+	sd.fields = make(map[string]interface{})
+	sd.fields["id"] = id
 	return sd
 }
 
-// SolrDocument struct the holds fields and provides methods for manipulating them
+// SolrDocument struct the holds fields and provides methods for manipulating them.
+// Field values keep their Go type so they are sent to Solr as native JSON types.
 type SolrDocument struct {
-	fields map[string][]string
+	fields   map[string]interface{}
+	children []SolrDocument
 }
 
 // SetField sets the value for a field in the SolrDocument
@@ -23,12 +28,176 @@ func (sd *SolrDocument) SetField(name string, values []string) {
 	sd.fields[name] = values
 }
 
-// GetField returns a field from the document if it exists
+// Set sets a field to any value. Slices are indexed as multivalued fields and time.Time
+// values are converted to the Solr date format.
+func (sd *SolrDocument) Set(name string, value interface{}) {
+	sd.fields[name] = value
+}
+
+// SetInt sets an integer field
+func (sd *SolrDocument) SetInt(name string, value int64) {
+	sd.fields[name] = value
+}
+
+// SetFloat sets a floating point field
+func (sd *SolrDocument) SetFloat(name string, value float64) {
+	sd.fields[name] = value
+}
+
+// SetBool sets a boolean field
+func (sd *SolrDocument) SetBool(name string, value bool) {
+	sd.fields[name] = value
+}
+
+// SetTime sets a date field
+func (sd *SolrDocument) SetTime(name string, value time.Time) {
+	sd.fields[name] = value
+}
+
+// SetLatLon sets a geo point field using the "lat,lon" format
+func (sd *SolrDocument) SetLatLon(name string, lat float64, lon float64) {
+	sd.fields[name] = strconv.FormatFloat(lat, 'f', -1, 64) + "," + strconv.FormatFloat(lon, 'f', -1, 64)
+}
+
+// AddValue appends a value to a multivalued field, creating the field if it doesn't exist
+func (sd *SolrDocument) AddValue(name string, value interface{}) {
+	switch existing := sd.fields[name].(type) {
+	case nil:
+		sd.fields[name] = []interface{}{value}
+	case []interface{}:
+		sd.fields[name] = append(existing, value)
+	case []string:
+		if s, ok := value.(string); ok {
+			sd.fields[name] = append(existing, s)
+			return
+		}
+		vals := make([]interface{}, 0, len(existing)+1)
+		for _, e := range existing {
+			vals = append(vals, e)
+		}
+		sd.fields[name] = append(vals, value)
+	default:
+		sd.fields[name] = []interface{}{existing, value}
+	}
+}
+
+// GetField returns a field from the document if it exists. Typed values are returned in their string form.
 func (sd *SolrDocument) GetField(name string) ([]string, error) {
-	if val, ok := sd.fields[name]; ok {
-		return val, nil
+	val, ok := sd.fields[name]
+	if !ok {
+		return nil, fmt.Errorf("Document id %s does not contain field named %s", sd.ID(), name)
+	}
+	switch v := val.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		strs := make([]string, len(v))
+		for i, e := range v {
+			strs[i] = valueString(e)
+		}
+		return strs, nil
+	}
+	return []string{valueString(val)}, nil
+}
+
+// Get returns the raw value of a field and whether it exists
+func (sd *SolrDocument) Get(name string) (interface{}, bool) {
+	val, ok := sd.fields[name]
+	return val, ok
+}
+
+// GetInt returns an integer field
+func (sd *SolrDocument) GetInt(name string) (int64, error) {
+	val, err := sd.singleValue(name)
+	if err != nil {
+		return 0, err
+	}
+	if s, ok := val.(string); ok {
+		return strconv.ParseInt(s, 10, 64)
+	}
+	if i, ok := toInt64(val); ok {
+		return i, nil
+	}
+	return 0, fmt.Errorf("Unable to convert field %s to int64", name)
+}
+
+// GetFloat returns a floating point field
+func (sd *SolrDocument) GetFloat(name string) (float64, error) {
+	val, err := sd.singleValue(name)
+	if err != nil {
+		return 0, err
+	}
+	if s, ok := val.(string); ok {
+		return strconv.ParseFloat(s, 64)
+	}
+	if f, ok := toFloat64(val); ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("Unable to convert field %s to float64", name)
+}
+
+// GetBool returns a boolean field
+func (sd *SolrDocument) GetBool(name string) (bool, error) {
+	val, err := sd.singleValue(name)
+	if err != nil {
+		return false, err
+	}
+	switch v := val.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, fmt.Errorf("Unable to convert field %s to bool", name)
+}
+
+// GetTime returns a date field
+func (sd *SolrDocument) GetTime(name string) (time.Time, error) {
+	val, err := sd.singleValue(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch v := val.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return ParseSolrDate(v)
 	}
-	return nil, fmt.Errorf("Document id %s does not contain field named %s", sd.fields["id"][0], name)
+	return time.Time{}, fmt.Errorf("Unable to convert field %s to time.Time", name)
+}
+
+// singleValue returns the value of a field, unwrapping single element multivalued fields
+func (sd *SolrDocument) singleValue(name string) (interface{}, error) {
+	val, ok := sd.fields[name]
+	if !ok {
+		return nil, fmt.Errorf("Document id %s does not contain field named %s", sd.ID(), name)
+	}
+	switch v := val.(type) {
+	case []string:
+		if len(v) != 1 {
+			return nil, fmt.Errorf("Field %s has %d values, expected 1", name, len(v))
+		}
+		return v[0], nil
+	case []interface{}:
+		if len(v) != 1 {
+			return nil, fmt.Errorf("Field %s has %d values, expected 1", name, len(v))
+		}
+		return v[0], nil
+	}
+	return val, nil
+}
+
+// valueString returns the string form of a field value
+func valueString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case time.Time:
+		return FormatSolrDate(s)
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
 }
 
 // Exists returns a bool. True = the field exists. False = the field does not exist.
@@ -37,21 +206,54 @@ func (sd *SolrDocument) Exists(name string) bool {
 	return present
 }
 
+// solrMap converts the document fields to values ready to be serialized for Solr
+func (sd *SolrDocument) solrMap() (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(sd.fields))
+	for name, val := range sd.fields {
+		v, err := solrValue(reflect.ValueOf(val))
+		if err != nil {
+			return nil, fmt.Errorf("Error converting field %s: %s", name, err)
+		}
+		m[name] = v
+	}
+	if len(sd.children) > 0 {
+		children := make([]interface{}, len(sd.children))
+		for i, c := range sd.children {
+			cm, err := c.solrMap()
+			if err != nil {
+				return nil, fmt.Errorf("Error converting child document %s: %s", c.ID(), err)
+			}
+			children[i] = cm
+		}
+		m["_childDocuments_"] = children
+	}
+	return m, nil
+}
+
 // SolrJSON returns the json representation of a solr document for indexing
 func (sd *SolrDocument) SolrJSON() (string, error) {
-	jsn, err := json.Marshal(sd.fields)
+	m, err := sd.solrMap()
+	if err != nil {
+		return "", err
+	}
+	jsn, err := json.Marshal(m)
 	return string(jsn), err
 }
 
 // ID returns the Id of the document
 func (sd *SolrDocument) ID() string {
-	if !sd.Exists("id") {
-		return ""
+	switch id := sd.fields["id"].(type) {
+	case string:
+		return id
+	case []string:
+		if len(id) > 0 {
+			return id[0]
+		}
 	}
-	return sd.fields["id"][0]
+	return ""
 }
 
 // SetID sets the Id of the document
 func (sd *SolrDocument) SetID(id string) {
-	sd.fields["id"] = []string{id}
+	sd.fields["id"] = id
 }
@@ -1,7 +1,10 @@
 package solrg
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 )
 
@@ -19,10 +22,26 @@ func NewSolrDocumentCollection() SolrDocumentCollection {
 
 // AddDoc adds a document to the collection
 func (sdc *SolrDocumentCollection) AddDoc(doc SolrDocument) error {
+	if err := validateDocIDs(doc); err != nil {
+		return err
+	}
+	sdc.docs[doc.ID()] = doc
+	return nil
+}
+
+// validateDocIDs makes sure a document and all of its children have an id
+func validateDocIDs(doc SolrDocument) error {
 	if doc.ID() == "" {
 		return fmt.Errorf("Document is missing an id! Please make sure all docs have an id field")
 	}
-	sdc.docs[doc.ID()] = doc
+	for _, c := range doc.allChildren() {
+		if c.ID() == "" {
+			return fmt.Errorf("Child of document %s is missing an id! Please make sure all child docs have an id field", doc.ID())
+		}
+		if err := validateDocIDs(c); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -33,19 +52,43 @@ func (sdc *SolrDocumentCollection) DeleteDoc(id string) {
 
 // SolrJSON returns a json string representation of the doc collection ready for Solr
 func (sdc *SolrDocumentCollection) SolrJSON() (string, error) {
-
-	jsn := ""
+	var jsn strings.Builder
 	for i, v := range sdc.docs {
 		djsn, err := v.SolrJSON()
 		if err != nil {
 			return "", fmt.Errorf("Error creating json string at position %s, error: %s", i, err)
 		}
-		jsn = jsn + djsn + ",\n"
+		if jsn.Len() > 0 {
+			jsn.WriteString(",\n")
+		}
+		jsn.WriteString(djsn)
 	}
-	if strings.HasSuffix(jsn, ",\n") {
-		jsn = strings.TrimRight(jsn, ",\n")
+	return jsn.String(), nil
+}
+
+// WriteJSON streams the doc collection to w as a json array, one document at a time
+func (sdc *SolrDocumentCollection) WriteJSON(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	bw.WriteByte('[')
+	first := true
+	for i, v := range sdc.docs {
+		m, err := v.solrMap()
+		if err != nil {
+			return fmt.Errorf("Error creating json at position %s, error: %s", i, err)
+		}
+		if !first {
+			bw.WriteByte(',')
+		}
+		first = false
+		if err := enc.Encode(m); err != nil {
+			return fmt.Errorf("Error creating json at position %s, error: %s", i, err)
+		}
 	}
-	return jsn, nil
+	bw.WriteByte(']')
+	return bw.Flush()
 }
 
 // GetDoc returns a doc by id
@@ -0,0 +1,107 @@
+package solrg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func benchDocs(n int) SolrDocumentCollection {
+	col := NewSolrDocumentCollection()
+	for i := 0; i < n; i++ {
+		doc := NewSolrDocument(fmt.Sprintf("doc-%d", i))
+		doc.SetField("title_t", []string{"a reasonably long title for a benchmark document"})
+		doc.SetInt("n_i", int64(i))
+		doc.SetFloat("price_d", float64(i)/3)
+		col.AddDoc(doc)
+	}
+	return col
+}
+
+// concatSolrJSON is the previous SolrJSON implementation, kept for comparison in benchmarks
+func concatSolrJSON(sdc *SolrDocumentCollection) string {
+	jsn := ""
+	for _, v := range sdc.docs {
+		djsn, _ := v.SolrJSON()
+		jsn = jsn + djsn + ",\n"
+	}
+	return strings.TrimRight(jsn, ",\n")
+}
+
+func TestWriteJSON(t *testing.T) {
+	col := benchDocs(50)
+	var buf bytes.Buffer
+	must(col.WriteJSON(&buf))
+
+	var docs []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &docs); err != nil {
+		t.Fatalf("WriteJSON produced invalid json: %s", err)
+	}
+	if len(docs) != 50 {
+		t.Errorf("Expected 50 docs, got %d", len(docs))
+	}
+
+	empty := NewSolrDocumentCollection()
+	buf.Reset()
+	must(empty.WriteJSON(&buf))
+	if buf.String() != "[]" {
+		t.Errorf("Expected an empty array, got %s", buf.String())
+	}
+}
+
+func TestPostDocsStreaming(t *testing.T) {
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var docs []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&docs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = len(docs)
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+	col := benchDocs(2000)
+	must(sc.PostDocs(&col, "test"))
+	if received != 2000 {
+		t.Errorf("Expected 2000 docs to be posted, got %d", received)
+	}
+}
+
+func BenchmarkSolrJSONConcat(b *testing.B) {
+	col := benchDocs(5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		jsn := []byte("[" + concatSolrJSON(&col) + "]")
+		io.Copy(ioutil.Discard, bytes.NewReader(jsn))
+	}
+}
+
+func BenchmarkSolrJSON(b *testing.B) {
+	col := benchDocs(5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		str, _ := col.SolrJSON()
+		jsn := []byte("[" + str + "]")
+		io.Copy(ioutil.Discard, bytes.NewReader(jsn))
+	}
+}
+
+func BenchmarkWriteJSON(b *testing.B) {
+	col := benchDocs(5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		col.WriteJSON(ioutil.Discard)
+	}
+}
@@ -0,0 +1,170 @@
+package solrg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// SolrError is returned when Solr answers a request with an error status. Use errors.As to get it from the
+// errors returned by the client, or the IsNotFound, IsBadRequest, IsConflict and IsUnavailable helpers.
+type SolrError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Code is the error code reported by Solr, usually the same as StatusCode
+	Code int
+	Msg  string
+	// Metadata holds the error metadata, including error-class and root-error-class
+	Metadata map[string]string
+	// ErrorClass is the Java exception class of the error
+	ErrorClass string
+	// RootErrorClass is the Java exception class of the root cause of the error
+	RootErrorClass string
+	// Trace is the Java stack trace, returned for server errors
+	Trace string
+	// Node is the URL of the Solr node that returned the error
+	Node string
+	// Path is the path of the failed request
+	Path string
+	// Body is the raw response body
+	Body []byte
+
+	// metadata key/value pairs in the order Solr returned them
+	metadataPairs []string
+}
+
+func (e *SolrError) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = string(e.Body)
+	}
+	return fmt.Sprintf("Error in Solr response from %s%s, status code = %d: %s", e.Node, e.Path, e.StatusCode, msg)
+}
+
+// solrErrorBody is the error section of a Solr JSON response
+type solrErrorBody struct {
+	Error struct {
+		// a flat list of key/value pairs, or an object when json.nl=map
+		Metadata json.RawMessage `json:"metadata"`
+		Msg      string          `json:"msg"`
+		Trace    string          `json:"trace"`
+		Code     int             `json:"code"`
+	} `json:"error"`
+}
+
+// newSolrError builds a SolrError from a failed response and its body
+func newSolrError(resp *http.Response, body []byte) *SolrError {
+	e := &SolrError{StatusCode: resp.StatusCode, Body: body}
+	if resp.Request != nil && resp.Request.URL != nil {
+		e.Node = resp.Request.URL.Scheme + "://" + resp.Request.URL.Host
+		e.Path = resp.Request.URL.Path
+	}
+
+	var errBody solrErrorBody
+	if json.Unmarshal(body, &errBody) != nil {
+		// with wt=javabin errors are javabin too
+		if len(body) > 0 && body[0] == javabinVersion {
+			e.readJavabin(body)
+		}
+		return e
+	}
+	e.Code = errBody.Error.Code
+	e.Msg = errBody.Error.Msg
+	e.Trace = errBody.Error.Trace
+	e.Metadata, e.metadataPairs = parseErrorMetadata(errBody.Error.Metadata)
+	e.ErrorClass = e.Metadata["error-class"]
+	e.RootErrorClass = e.Metadata["root-error-class"]
+	return e
+}
+
+// readJavabin fills in the error details from the error section of a javabin response
+func (e *SolrError) readJavabin(body []byte) {
+	val, err := UnmarshalJavabin(body)
+	if err != nil {
+		return
+	}
+	errList := asNamedList(val).getList("error")
+	if msg, ok := errList.Get("msg"); ok {
+		e.Msg, _ = msg.(string)
+	}
+	if trace, ok := errList.Get("trace"); ok {
+		e.Trace, _ = trace.(string)
+	}
+	if code, ok := errList.Get("code"); ok {
+		n, _ := toInt64(code)
+		e.Code = int(n)
+	}
+	metadata := errList.getList("metadata")
+	if len(metadata) > 0 {
+		e.Metadata = make(map[string]string, len(metadata))
+		for _, m := range metadata {
+			v := fmt.Sprint(m.Value)
+			e.Metadata[m.Name] = v
+			e.metadataPairs = append(e.metadataPairs, m.Name, v)
+		}
+		e.ErrorClass = e.Metadata["error-class"]
+		e.RootErrorClass = e.Metadata["root-error-class"]
+	}
+}
+
+// readSolrError reads the body of a failed response and builds a SolrError from it
+func readSolrError(resp *http.Response) *SolrError {
+	body, _ := ioutil.ReadAll(resp.Body)
+	return newSolrError(resp, body)
+}
+
+// parseErrorMetadata reads error metadata in either the flat list or the object format
+func parseErrorMetadata(raw json.RawMessage) (map[string]string, []string) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	metadata := make(map[string]string)
+	var pairs []string
+	if json.Unmarshal(raw, &pairs) == nil {
+		for i := 0; i+1 < len(pairs); i += 2 {
+			metadata[pairs[i]] = pairs[i+1]
+		}
+		return metadata, pairs
+	}
+	json.Unmarshal(raw, &metadata)
+	for k, v := range metadata {
+		pairs = append(pairs, k, v)
+	}
+	return metadata, pairs
+}
+
+func hasStatus(err error, statusCodes ...int) bool {
+	var se *SolrError
+	if !errors.As(err, &se) {
+		return false
+	}
+	for _, code := range statusCodes {
+		if se.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound reports whether err is a 404 from Solr or a *DocumentNotFoundError
+func IsNotFound(err error) bool {
+	var nf *DocumentNotFoundError
+	return errors.As(err, &nf) || hasStatus(err, http.StatusNotFound)
+}
+
+// IsBadRequest reports whether Solr rejected a request as invalid
+func IsBadRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest)
+}
+
+// IsConflict reports whether Solr rejected a request because of a conflict, e.g. a *VersionConflictError
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsUnavailable reports whether Solr or a node behind it was unavailable. These errors are usually temporary.
+func IsUnavailable(err error) bool {
+	return hasStatus(err, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout)
+}
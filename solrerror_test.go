@@ -0,0 +1,112 @@
+package solrg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSolrError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/solr/test/select":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"responseHeader":{"status":400,"QTime":0},"error":{"metadata":["error-class","org.apache.solr.common.SolrException","root-error-class","org.apache.solr.parser.ParseException"],"msg":"org.apache.solr.search.SyntaxError: Cannot parse 'title:('","code":400}}`)
+		case "/solr/test/update":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":{"metadata":{"error-class":"org.apache.solr.common.SolrException"},"msg":"No registered leader was found","trace":"org.apache.solr.common.SolrException: No registered leader\n\tat ...","code":503}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<html><body>Not Found</body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://") + "/solr")
+	must(err)
+
+	_, err = sc.Query("test", "select", &SolrParams{Q: "title:("}, time.Second)
+	var se *SolrError
+	if !errors.As(err, &se) {
+		t.Fatalf("Expected a *SolrError, got %T: %s", err, err)
+	}
+	if !IsBadRequest(err) || IsNotFound(err) || se.Code != 400 {
+		t.Errorf("Unexpected status %d/%d", se.StatusCode, se.Code)
+	}
+	if se.ErrorClass != "org.apache.solr.common.SolrException" || se.RootErrorClass != "org.apache.solr.parser.ParseException" {
+		t.Errorf("Unexpected error classes %s, %s", se.ErrorClass, se.RootErrorClass)
+	}
+	if se.Node != srv.URL || se.Path != "/solr/test/select" || !strings.Contains(se.Msg, "Cannot parse") {
+		t.Errorf("Unexpected error %+v", se)
+	}
+
+	err = sc.Commit("test")
+	if !IsUnavailable(err) || !errors.As(err, &se) {
+		t.Fatalf("Expected an unavailable error, got %s", err)
+	}
+	if se.ErrorClass != "org.apache.solr.common.SolrException" || !strings.HasPrefix(se.Trace, "org.apache.solr.common.SolrException") {
+		t.Errorf("Unexpected error %+v", se)
+	}
+	if !retryable(err) {
+		t.Error("Unavailable errors should be retried")
+	}
+
+	_, err = sc.Query("missing", "select", &SolrParams{Q: "*:*"}, time.Second)
+	if !IsNotFound(err) || !errors.As(err, &se) || !strings.Contains(err.Error(), "Not Found") {
+		t.Errorf("Expected a not found error with the raw body, got %s", err)
+	}
+	if retryable(err) {
+		t.Error("Not found errors should not be retried")
+	}
+
+	if IsNotFound(fmt.Errorf("connection refused")) || IsConflict(nil) {
+		t.Error("Only Solr errors should match")
+	}
+	if !IsNotFound(fmt.Errorf("wrapped: %w", &DocumentNotFoundError{Collection: "test", ID: "1"})) {
+		t.Error("DocumentNotFoundError should be a not found error")
+	}
+}
+
+func TestSolrErrorJavabin(t *testing.T) {
+	body, err := MarshalJavabin(NamedList{
+		{Name: "responseHeader", Value: NamedList{{Name: "status", Value: int32(400)}, {Name: "QTime", Value: int32(1)}}},
+		{Name: "error", Value: NamedList{
+			{Name: "metadata", Value: NamedList{
+				{Name: "error-class", Value: "org.apache.solr.common.SolrException"},
+				{Name: "root-error-class", Value: "org.apache.solr.parser.ParseException"},
+			}},
+			{Name: "msg", Value: "org.apache.solr.search.SyntaxError: Cannot parse 'title:('"},
+			{Name: "code", Value: int32(400)},
+		}},
+	})
+	must(err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+	sc.SetJavabin(true)
+
+	_, queryErr := sc.Query("test", "select", &SolrParams{Q: "title:("}, time.Second)
+	_, getErr := sc.Get("test", []string{"1"})
+	for _, err := range []error{queryErr, getErr} {
+		var se *SolrError
+		if !errors.As(err, &se) {
+			t.Fatalf("Expected a *SolrError, got %T: %s", err, err)
+		}
+		if se.Code != 400 || !strings.HasPrefix(se.Msg, "org.apache.solr.search.SyntaxError") || se.RootErrorClass != "org.apache.solr.parser.ParseException" {
+			t.Errorf("Unexpected javabin error %+v", se)
+		}
+		if !strings.HasSuffix(err.Error(), "Cannot parse 'title:('") {
+			t.Errorf("Unexpected error message %s", err)
+		}
+	}
+}
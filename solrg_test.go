@@ -1,6 +1,7 @@
 package solrg
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
@@ -143,6 +144,91 @@ func TestSolrDocument(t *testing.T) {
 
 }
 
+func TestSolrDocumentTypedFields(t *testing.T) {
+	published := time.Date(2018, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	sd := NewSolrDocument("1")
+	sd.SetInt("count_i", 42)
+	sd.SetFloat("price_d", 9.99)
+	sd.SetBool("active_b", true)
+	sd.SetTime("published_dt", published)
+	sd.SetLatLon("location_p", 45.17614, -93.87341)
+	sd.AddValue("sizes_is", 1)
+	sd.AddValue("sizes_is", 2)
+	sd.SetField("tags_ss", []string{"a"})
+	sd.AddValue("tags_ss", "b")
+
+	jsn, err := sd.SolrJSON()
+	must(err)
+	var got map[string]interface{}
+	must(json.Unmarshal([]byte(jsn), &got))
+
+	expected := map[string]interface{}{
+		"id":           "1",
+		"count_i":      float64(42),
+		"price_d":      9.99,
+		"active_b":     true,
+		"published_dt": "2018-07-01T12:00:00Z",
+		"location_p":   "45.17614,-93.87341",
+		"sizes_is":     []interface{}{float64(1), float64(2)},
+		"tags_ss":      []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected json.\nexpected: %v\ngot:      %v", expected, got)
+	}
+
+	if n, err := sd.GetInt("count_i"); err != nil || n != 42 {
+		t.Errorf("GetInt returned %d, %v", n, err)
+	}
+	if f, err := sd.GetFloat("price_d"); err != nil || f != 9.99 {
+		t.Errorf("GetFloat returned %f, %v", f, err)
+	}
+	if b, err := sd.GetBool("active_b"); err != nil || !b {
+		t.Errorf("GetBool returned %t, %v", b, err)
+	}
+	if ts, err := sd.GetTime("published_dt"); err != nil || !ts.Equal(published) {
+		t.Errorf("GetTime returned %s, %v", ts, err)
+	}
+	if vals, _ := sd.GetField("count_i"); !reflect.DeepEqual(vals, []string{"42"}) {
+		t.Errorf("GetField should return typed values as strings, got %v", vals)
+	}
+	if _, err := sd.GetInt("sizes_is"); err == nil {
+		t.Error("Expected an error calling GetInt on a field with 2 values")
+	}
+}
+
+func TestSolrDocumentAtomicUpdates(t *testing.T) {
+	sd := NewSolrDocument("1")
+	sd.SetAtomic("title_t", "new title")
+	sd.SetAtomic("obsolete_s", nil)
+	sd.Inc("views_i", 1)
+	sd.AddToField("tags_ss", []string{"a", "b"})
+	sd.AddDistinct("cats_ss", "c")
+	sd.Remove("old_ss", "x")
+	sd.RemoveRegex("codes_ss", "^tmp.*")
+	sd.Set("updated_dt", AtomicSet(time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)))
+
+	jsn, err := sd.SolrJSON()
+	must(err)
+	var got map[string]interface{}
+	must(json.Unmarshal([]byte(jsn), &got))
+
+	expected := map[string]interface{}{
+		"id":         "1",
+		"title_t":    map[string]interface{}{"set": "new title"},
+		"obsolete_s": map[string]interface{}{"set": nil},
+		"views_i":    map[string]interface{}{"inc": float64(1)},
+		"tags_ss":    map[string]interface{}{"add": []interface{}{"a", "b"}},
+		"cats_ss":    map[string]interface{}{"add-distinct": "c"},
+		"old_ss":     map[string]interface{}{"remove": "x"},
+		"codes_ss":   map[string]interface{}{"removeregex": "^tmp.*"},
+		"updated_dt": map[string]interface{}{"set": "2018-07-01T00:00:00Z"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected json.\nexpected: %v\ngot:      %v", expected, got)
+	}
+}
+
 func TestLBNodes(t *testing.T) {
 
 	sc, err := NewSolrClient("localhost:9983")
@@ -0,0 +1,130 @@
+package solrg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+// GetOptions holds the optional parameters of a real-time get request.
+// See https://lucene.apache.org/solr/guide/7_4/realtime-get.html
+type GetOptions struct {
+	// Fields limits the returned fields
+	Fields []string `url:"fl,comma,omitempty"`
+	// Route is the _route_ of the documents. It lets Solr ask only the shard holding them when the
+	// collection uses the implicit router or composite ids.
+	Route string `url:"_route_,omitempty"`
+	// Filters only return the documents matching all of the filter queries
+	Filters []string `url:"fq,omitempty"`
+}
+
+// DocumentNotFoundError is returned when a document requested by id doesn't exist
+type DocumentNotFoundError struct {
+	Collection string
+	ID         string
+}
+
+func (e *DocumentNotFoundError) Error() string {
+	return fmt.Sprintf("Document %s not found in %s", e.ID, e.Collection)
+}
+
+// Get fetches the latest version of documents by id using the /get handler, including changes that
+// haven't been committed yet. Documents that don't exist are left out of the result.
+func (sc *SolrClient) Get(collection string, ids []string, fields ...string) ([]SolrSearchDocument, error) {
+	return sc.GetWithOptions(collection, ids, GetOptions{Fields: fields})
+}
+
+// GetWithOptions fetches the latest version of documents by id, e.g. with a _route_
+func (sc *SolrClient) GetWithOptions(collection string, ids []string, opts GetOptions) ([]SolrSearchDocument, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	params, err := query.Values(opts)
+	if err != nil {
+		return nil, err
+	}
+	// repeated id params, unlike ids=a,b, allow commas in ids
+	params["id"] = ids
+	javabin := sc.usesJavabin()
+	if javabin {
+		params.Set("wt", "javabin")
+	} else {
+		params.Set("wt", "json")
+	}
+	url := "http://" + sc.LBNodeAddress() + "/" + collection + "/get?" + params.Encode()
+
+	var client = &http.Client{
+		Timeout: time.Second * 10,
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, readSolrError(resp)
+	}
+
+	if javabin {
+		val, err := NewJavabinDecoder(resp.Body).Decode()
+		if err != nil {
+			return nil, fmt.Errorf("Error decoding javabin response: %s", err)
+		}
+		// a single id is answered with the doc itself, null if it doesn't exist
+		if doc, ok := asNamedList(val).Get("doc"); ok {
+			if doc, ok := doc.(SolrSearchDocument); ok {
+				return []SolrSearchDocument{doc}, nil
+			}
+			return nil, nil
+		}
+		getResp, err := javabinSearchResponse(val)
+		if err != nil {
+			return nil, err
+		}
+		return getResp.Response.Docs, nil
+	}
+
+	// _version_ values don't fit in a float64, keep numbers as json.Number
+	var getResp struct {
+		// Doc is set instead of Response when a single id is requested, null if it doesn't exist
+		Doc      SolrSearchDocument `json:"doc"`
+		Response struct {
+			Docs []SolrSearchDocument `json:"docs"`
+		} `json:"response"`
+	}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&getResp); err != nil {
+		return nil, err
+	}
+	if getResp.Doc != nil {
+		return []SolrSearchDocument{getResp.Doc}, nil
+	}
+	return getResp.Response.Docs, nil
+}
+
+// GetDoc fetches the latest version of a single document. A *DocumentNotFoundError is returned if it doesn't exist.
+func (sc *SolrClient) GetDoc(collection string, id string, fields ...string) (SolrSearchDocument, error) {
+	docs, err := sc.Get(collection, []string{id}, fields...)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, &DocumentNotFoundError{Collection: collection, ID: id}
+	}
+	return docs[0], nil
+}
+
+// GetDocInto fetches the latest version of a single document and decodes it into a tagged struct
+// (see UnmarshalSolrDoc). A *DocumentNotFoundError is returned if it doesn't exist.
+func (sc *SolrClient) GetDocInto(collection string, id string, v interface{}) error {
+	doc, err := sc.GetDoc(collection, id)
+	if err != nil {
+		return err
+	}
+	return doc.Decode(v)
+}
@@ -0,0 +1,104 @@
+package solrg
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRealtimeGet(t *testing.T) {
+	stored := map[string]string{
+		"1":   `{"id":"1","title_t":["first"],"_version_":1612345678901234567}`,
+		"a,b": `{"id":"a,b","title_t":["comma"],"_version_":2}`,
+	}
+	var lastQuery map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/test/get" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		lastQuery = r.URL.Query()
+		// like Solr, a single id gets the doc itself rather than a document list
+		if ids := lastQuery["id"]; len(ids) == 1 {
+			doc, ok := stored[ids[0]]
+			if !ok {
+				doc = "null"
+			}
+			fmt.Fprintf(w, `{"doc":%s}`, doc)
+			return
+		}
+		var docs []string
+		for _, id := range lastQuery["id"] {
+			if doc, ok := stored[id]; ok {
+				docs = append(docs, doc)
+			}
+		}
+		fmt.Fprintf(w, `{"response":{"numFound":%d,"start":0,"docs":[%s]}}`, len(docs), strings.Join(docs, ","))
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	docs, err := sc.Get("test", []string{"1", "missing", "a,b"}, "id", "title_t")
+	must(err)
+	if len(docs) != 2 || docs[1].String("id") != "a,b" {
+		t.Errorf("Unexpected docs %v", docs)
+	}
+	if lastQuery["fl"][0] != "id,title_t" || len(lastQuery["id"]) != 3 {
+		t.Errorf("Unexpected params %v", lastQuery)
+	}
+	if v, _ := docs[0].Version(); v != 1612345678901234567 {
+		t.Errorf("Version lost precision: %d", v)
+	}
+
+	_, err = sc.GetWithOptions("test", []string{"1"}, GetOptions{Route: "shard1", Filters: []string{"type_s:book"}})
+	must(err)
+	if lastQuery["_route_"][0] != "shard1" || lastQuery["fq"][0] != "type_s:book" {
+		t.Errorf("Unexpected params %v", lastQuery)
+	}
+
+	_, err = sc.GetDoc("test", "missing")
+	if nf, ok := err.(*DocumentNotFoundError); !ok || nf.ID != "missing" {
+		t.Errorf("Expected a DocumentNotFoundError, got %v", err)
+	}
+
+	var book struct {
+		ID     string   `solr:"id"`
+		Titles []string `solr:"title_t"`
+	}
+	must(sc.GetDocInto("test", "1", &book))
+	if book.ID != "1" || book.Titles[0] != "first" {
+		t.Errorf("Unexpected struct %+v", book)
+	}
+}
+
+func TestRealtimeGetJavabin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rsp NamedList
+		if r.URL.Query().Get("id") == "1" {
+			rsp = NamedList{{Name: "doc", Value: SolrSearchDocument{"id": "1", "_version_": int64(1612345678901234567)}}}
+		} else {
+			rsp = NamedList{{Name: "doc", Value: nil}}
+		}
+		b, err := MarshalJavabin(rsp)
+		must(err)
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+	sc.SetJavabin(true)
+
+	doc, err := sc.GetDoc("test", "1")
+	must(err)
+	if v, _ := doc.Version(); v != 1612345678901234567 {
+		t.Errorf("Unexpected doc %v", doc)
+	}
+	if _, err := sc.GetDoc("test", "missing"); !IsNotFound(err) {
+		t.Errorf("Expected a DocumentNotFoundError, got %v", err)
+	}
+}
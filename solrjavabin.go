@@ -0,0 +1,1045 @@
+package solrg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// javabin type tags, see org.apache.solr.common.util.JavaBinCodec
+const (
+	javabinVersion = 2
+
+	jbNull           = 0
+	jbBoolTrue       = 1
+	jbBoolFalse      = 2
+	jbByte           = 3
+	jbShort          = 4
+	jbDouble         = 5
+	jbInt            = 6
+	jbLong           = 7
+	jbFloat          = 8
+	jbDate           = 9
+	jbMap            = 10
+	jbSolrDoc        = 11
+	jbSolrDocList    = 12
+	jbByteArr        = 13
+	jbIterator       = 14
+	jbEnd            = 15
+	jbSolrInputDoc   = 16
+	jbMapEntryIter   = 17
+	jbEnumFieldValue = 18
+	jbMapEntry       = 19
+
+	// tags that combine the type and a size or value in a single byte
+	jbStr          = 1 << 5
+	jbSInt         = 2 << 5
+	jbSLong        = 3 << 5
+	jbArr          = 4 << 5
+	jbOrderedMap   = 5 << 5
+	jbNamedList    = 6 << 5
+	jbExternString = 7 << 5
+)
+
+// NamedListEntry is a single name/value pair of a NamedList
+type NamedListEntry struct {
+	Name  string
+	Value interface{}
+}
+
+// NamedList is an ordered list of name/value pairs that may repeat names, as used throughout Solr responses.
+// It is encoded with the javabin NAMED_LST tag.
+type NamedList []NamedListEntry
+
+// SimpleOrderedMap is a NamedList that Solr serializes like a map. It is encoded with the javabin ORDERED_MAP tag.
+type SimpleOrderedMap []NamedListEntry
+
+// Get returns the value of the first entry with a name
+func (nl NamedList) Get(name string) (interface{}, bool) {
+	for _, e := range nl {
+		if e.Name == name {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Get returns the value of the first entry with a name
+func (m SimpleOrderedMap) Get(name string) (interface{}, bool) {
+	return NamedList(m).Get(name)
+}
+
+// JavabinDocList is a javabin document list, the "response" section of a query response
+type JavabinDocList struct {
+	NumFound      int64
+	Start         int64
+	MaxScore      *float32
+	NumFoundExact *bool
+	Docs          []SolrSearchDocument
+}
+
+// JavabinEnumValue is a value of an enum field
+type JavabinEnumValue struct {
+	Value int32
+	Name  string
+}
+
+// javabinIterator is encoded as an ITERATOR, a sequence of values terminated by END
+type javabinIterator []interface{}
+
+// javabinEnd marks the end of an iterator while decoding
+type javabinEnd struct{}
+
+// JavabinDecoder reads values in Solr's javabin format.
+// See https://lucene.apache.org/solr/guide/7_4/response-writers.html#javabin-response-writer
+type JavabinDecoder struct {
+	r             *bufio.Reader
+	externStrings []string
+}
+
+// NewJavabinDecoder returns a decoder reading from r
+func NewJavabinDecoder(r io.Reader) *JavabinDecoder {
+	return &JavabinDecoder{r: bufio.NewReader(r)}
+}
+
+// UnmarshalJavabin decodes a complete javabin message
+func UnmarshalJavabin(data []byte) (interface{}, error) {
+	return NewJavabinDecoder(bytes.NewReader(data)).Decode()
+}
+
+// Decode reads the version byte and the value that follows it. Javabin types are decoded as:
+// NAMED_LST to NamedList, ORDERED_MAP to SimpleOrderedMap, MAP to map[string]interface{},
+// SOLRDOC to SolrSearchDocument, SOLRDOCLST to *JavabinDocList, SOLRINPUTDOC to SolrDocument,
+// arrays and iterators to []interface{}, DATE to time.Time, INT to int32 and LONG to int64.
+func (d *JavabinDecoder) Decode() (interface{}, error) {
+	version, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if version != javabinVersion {
+		return nil, fmt.Errorf("Unsupported javabin version %d, expected %d", version, javabinVersion)
+	}
+	d.externStrings = nil
+	return d.readVal()
+}
+
+func (d *JavabinDecoder) readVal() (interface{}, error) {
+	tag, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	return d.readValWithTag(tag)
+}
+
+func (d *JavabinDecoder) readValWithTag(tag byte) (interface{}, error) {
+	switch tag & 0xe0 {
+	case jbStr:
+		size, err := d.readSize(tag)
+		if err != nil {
+			return nil, err
+		}
+		return d.readStr(size)
+	case jbSInt:
+		return d.readSmallInt(tag)
+	case jbSLong:
+		return d.readSmallLong(tag)
+	case jbArr:
+		size, err := d.readSize(tag)
+		if err != nil {
+			return nil, err
+		}
+		return d.readArray(size)
+	case jbOrderedMap:
+		nl, err := d.readNamedList(tag)
+		return SimpleOrderedMap(nl), err
+	case jbNamedList:
+		return d.readNamedList(tag)
+	case jbExternString:
+		return d.readExternString(tag)
+	}
+
+	switch tag {
+	case jbNull:
+		return nil, nil
+	case jbBoolTrue:
+		return true, nil
+	case jbBoolFalse:
+		return false, nil
+	case jbByte:
+		b, err := d.r.ReadByte()
+		return int8(b), err
+	case jbShort:
+		var v int16
+		err := binary.Read(d.r, binary.BigEndian, &v)
+		return v, err
+	case jbDouble:
+		var v float64
+		err := binary.Read(d.r, binary.BigEndian, &v)
+		return v, err
+	case jbInt:
+		var v int32
+		err := binary.Read(d.r, binary.BigEndian, &v)
+		return v, err
+	case jbLong:
+		var v int64
+		err := binary.Read(d.r, binary.BigEndian, &v)
+		return v, err
+	case jbFloat:
+		var v float32
+		err := binary.Read(d.r, binary.BigEndian, &v)
+		return v, err
+	case jbDate:
+		var ms int64
+		if err := binary.Read(d.r, binary.BigEndian, &ms); err != nil {
+			return nil, err
+		}
+		// nanoseconds since the epoch overflow for dates past 2262, like Solr's 9999-12-31T23:59:59Z
+		return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)).UTC(), nil
+	case jbMap:
+		size, err := d.readVInt()
+		if err != nil {
+			return nil, err
+		}
+		return d.readMap(int(size))
+	case jbSolrDoc:
+		return d.readSolrDocument()
+	case jbSolrDocList:
+		return d.readSolrDocumentList()
+	case jbByteArr:
+		size, err := d.readVInt()
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size)
+		_, err = io.ReadFull(d.r, buf)
+		return buf, err
+	case jbIterator:
+		var vals []interface{}
+		for {
+			v, err := d.readVal()
+			if err != nil {
+				return nil, err
+			}
+			if _, end := v.(javabinEnd); end {
+				return vals, nil
+			}
+			vals = append(vals, v)
+		}
+	case jbEnd:
+		return javabinEnd{}, nil
+	case jbSolrInputDoc:
+		return d.readSolrInputDocument()
+	case jbMapEntryIter:
+		m := make(map[string]interface{})
+		for {
+			k, err := d.readVal()
+			if err != nil {
+				return nil, err
+			}
+			if _, end := k.(javabinEnd); end {
+				return m, nil
+			}
+			v, err := d.readVal()
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = v
+		}
+	case jbEnumFieldValue:
+		v, err := d.readVal()
+		if err != nil {
+			return nil, err
+		}
+		name, err := d.readVal()
+		if err != nil {
+			return nil, err
+		}
+		i, _ := v.(int32)
+		s, _ := name.(string)
+		return JavabinEnumValue{Value: i, Name: s}, nil
+	case jbMapEntry:
+		k, err := d.readVal()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.readVal()
+		if err != nil {
+			return nil, err
+		}
+		return NamedListEntry{Name: fmt.Sprint(k), Value: v}, nil
+	}
+	return nil, fmt.Errorf("Unknown javabin type tag %d", tag)
+}
+
+func (d *JavabinDecoder) readSize(tag byte) (int, error) {
+	size := int(tag & 0x1f)
+	if size == 0x1f {
+		n, err := d.readVInt()
+		if err != nil {
+			return 0, err
+		}
+		size += int(n)
+	}
+	return size, nil
+}
+
+func (d *JavabinDecoder) readVInt() (uint32, error) {
+	v, err := d.readVLong()
+	return uint32(v), err
+}
+
+func (d *JavabinDecoder) readVLong() (uint64, error) {
+	var v uint64
+	for shift := uint(0); ; shift += 7 {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+}
+
+func (d *JavabinDecoder) readSmallInt(tag byte) (int32, error) {
+	v := int32(tag & 0x0f)
+	if tag&0x10 != 0 {
+		n, err := d.readVInt()
+		if err != nil {
+			return 0, err
+		}
+		v = int32(n<<4) | v
+	}
+	return v, nil
+}
+
+func (d *JavabinDecoder) readSmallLong(tag byte) (int64, error) {
+	v := int64(tag & 0x0f)
+	if tag&0x10 != 0 {
+		n, err := d.readVLong()
+		if err != nil {
+			return 0, err
+		}
+		v = int64(n<<4) | v
+	}
+	return v, nil
+}
+
+func (d *JavabinDecoder) readStr(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (d *JavabinDecoder) readExternString(tag byte) (string, error) {
+	idx, err := d.readSize(tag)
+	if err != nil {
+		return "", err
+	}
+	if idx != 0 {
+		if idx > len(d.externStrings) {
+			return "", fmt.Errorf("Invalid javabin extern string reference %d", idx)
+		}
+		return d.externStrings[idx-1], nil
+	}
+	strTag, err := d.r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	size, err := d.readSize(strTag)
+	if err != nil {
+		return "", err
+	}
+	s, err := d.readStr(size)
+	if err != nil {
+		return "", err
+	}
+	d.externStrings = append(d.externStrings, s)
+	return s, nil
+}
+
+func (d *JavabinDecoder) readArray(size int) ([]interface{}, error) {
+	vals := make([]interface{}, size)
+	for i := range vals {
+		v, err := d.readVal()
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func (d *JavabinDecoder) readName() (string, error) {
+	v, err := d.readVal()
+	if err != nil {
+		return "", err
+	}
+	if v == nil {
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("Expected a javabin string name but got %T", v)
+	}
+	return s, nil
+}
+
+func (d *JavabinDecoder) readNamedList(tag byte) (NamedList, error) {
+	size, err := d.readSize(tag)
+	if err != nil {
+		return nil, err
+	}
+	nl := make(NamedList, size)
+	for i := range nl {
+		if nl[i].Name, err = d.readName(); err != nil {
+			return nil, err
+		}
+		if nl[i].Value, err = d.readVal(); err != nil {
+			return nil, err
+		}
+	}
+	return nl, nil
+}
+
+func (d *JavabinDecoder) readMap(size int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, size)
+	for i := 0; i < size; i++ {
+		k, err := d.readVal()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.readVal()
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprint(k)] = v
+	}
+	return m, nil
+}
+
+func (d *JavabinDecoder) readSolrDocument() (SolrSearchDocument, error) {
+	tag, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	size, err := d.readSize(tag)
+	if err != nil {
+		return nil, err
+	}
+	doc := make(SolrSearchDocument, size)
+	var children []interface{}
+	for i := 0; i < size; i++ {
+		// either a field name or a child document
+		obj, err := d.readVal()
+		if err != nil {
+			return nil, err
+		}
+		if child, ok := obj.(SolrSearchDocument); ok {
+			children = append(children, child)
+			continue
+		}
+		name, ok := obj.(string)
+		if !ok {
+			return nil, fmt.Errorf("Expected a javabin field name but got %T", obj)
+		}
+		if doc[name], err = d.readVal(); err != nil {
+			return nil, err
+		}
+	}
+	if children != nil {
+		doc["_childDocuments_"] = children
+	}
+	return doc, nil
+}
+
+func (d *JavabinDecoder) readSolrDocumentList() (*JavabinDocList, error) {
+	header, err := d.readVal()
+	if err != nil {
+		return nil, err
+	}
+	vals, ok := header.([]interface{})
+	if !ok || len(vals) < 3 {
+		return nil, fmt.Errorf("Invalid javabin document list header")
+	}
+	dl := &JavabinDocList{}
+	dl.NumFound, _ = toInt64(vals[0])
+	dl.Start, _ = toInt64(vals[1])
+	if ms, ok := vals[2].(float32); ok {
+		dl.MaxScore = &ms
+	}
+	if len(vals) > 3 {
+		if exact, ok := vals[3].(bool); ok {
+			dl.NumFoundExact = &exact
+		}
+	}
+
+	docs, err := d.readVal()
+	if err != nil {
+		return nil, err
+	}
+	docVals, _ := docs.([]interface{})
+	dl.Docs = make([]SolrSearchDocument, 0, len(docVals))
+	for _, v := range docVals {
+		doc, ok := v.(SolrSearchDocument)
+		if !ok {
+			return nil, fmt.Errorf("Expected a javabin document but got %T", v)
+		}
+		dl.Docs = append(dl.Docs, doc)
+	}
+	return dl, nil
+}
+
+func (d *JavabinDecoder) readSolrInputDocument() (SolrDocument, error) {
+	sd := SolrDocument{fields: make(map[string]interface{})}
+	size, err := d.readVInt()
+	if err != nil {
+		return sd, err
+	}
+	// document boost, no longer used
+	if _, err := d.readVal(); err != nil {
+		return sd, err
+	}
+	for i := 0; i < int(size); i++ {
+		obj, err := d.readVal()
+		if err != nil {
+			return sd, err
+		}
+		if _, boost := obj.(float32); boost {
+			// field boost written by old clients, the field name follows
+			if obj, err = d.readVal(); err != nil {
+				return sd, err
+			}
+		}
+		if child, ok := obj.(SolrDocument); ok {
+			sd.children = append(sd.children, child)
+			continue
+		}
+		name, ok := obj.(string)
+		if !ok {
+			return sd, fmt.Errorf("Expected a javabin field name but got %T", obj)
+		}
+		if sd.fields[name], err = d.readVal(); err != nil {
+			return sd, err
+		}
+	}
+	return sd, nil
+}
+
+// JavabinEncoder writes values in Solr's javabin format
+type JavabinEncoder struct {
+	w             *bufio.Writer
+	externStrings map[string]int
+}
+
+// NewJavabinEncoder returns an encoder writing to w
+func NewJavabinEncoder(w io.Writer) *JavabinEncoder {
+	return &JavabinEncoder{w: bufio.NewWriter(w)}
+}
+
+// MarshalJavabin encodes v as a complete javabin message
+func MarshalJavabin(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewJavabinEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encode writes the version byte followed by v. Go values are encoded as:
+// NamedList to NAMED_LST, SimpleOrderedMap to ORDERED_MAP, maps to MAP, SolrSearchDocument to SOLRDOC,
+// *JavabinDocList to SOLRDOCLST, SolrDocument to SOLRINPUTDOC, slices to ARR, time.Time to DATE,
+// int8/int16/int32 to BYTE/SHORT/INT and int/int64 to LONG. Map keys and document fields are written
+// in sorted order.
+func (e *JavabinEncoder) Encode(v interface{}) error {
+	e.externStrings = nil
+	e.w.WriteByte(javabinVersion)
+	if err := e.writeVal(v); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *JavabinEncoder) writeVal(v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		return e.w.WriteByte(jbNull)
+	case bool:
+		if val {
+			return e.w.WriteByte(jbBoolTrue)
+		}
+		return e.w.WriteByte(jbBoolFalse)
+	case string:
+		e.writeStr(val)
+	case int8:
+		e.w.WriteByte(jbByte)
+		e.w.WriteByte(byte(val))
+	case uint8:
+		e.writeInt(int32(val))
+	case int16:
+		e.w.WriteByte(jbShort)
+		binary.Write(e.w, binary.BigEndian, val)
+	case uint16:
+		e.writeInt(int32(val))
+	case int32:
+		e.writeInt(val)
+	case uint32:
+		e.writeLong(int64(val))
+	case int:
+		e.writeLong(int64(val))
+	case int64:
+		e.writeLong(val)
+	case uint64:
+		if val > math.MaxInt64 {
+			return fmt.Errorf("Value %d is too large for javabin", val)
+		}
+		e.writeLong(int64(val))
+	case float32:
+		e.writeFloat(val)
+	case float64:
+		e.w.WriteByte(jbDouble)
+		binary.Write(e.w, binary.BigEndian, math.Float64bits(val))
+	case time.Time:
+		e.w.WriteByte(jbDate)
+		binary.Write(e.w, binary.BigEndian, val.Unix()*1000+int64(val.Nanosecond()/int(time.Millisecond)))
+	case []byte:
+		e.w.WriteByte(jbByteArr)
+		e.writeVInt(uint64(len(val)))
+		e.w.Write(val)
+	case NamedList:
+		return e.writeNamedList(jbNamedList, val)
+	case SimpleOrderedMap:
+		return e.writeNamedList(jbOrderedMap, NamedList(val))
+	case NamedListEntry:
+		e.w.WriteByte(jbMapEntry)
+		e.writeExternString(val.Name)
+		return e.writeVal(val.Value)
+	case JavabinEnumValue:
+		e.w.WriteByte(jbEnumFieldValue)
+		e.writeInt(val.Value)
+		e.writeStr(val.Name)
+	case SolrSearchDocument:
+		return e.writeSolrDocument(val)
+	case *JavabinDocList:
+		return e.writeSolrDocumentList(val)
+	case SolrDocument:
+		return e.writeSolrInputDocument(val)
+	case *SolrDocument:
+		return e.writeSolrInputDocument(*val)
+	case AtomicUpdate:
+		return e.writeVal(map[string]interface{}{val.Op: val.Value})
+	case javabinIterator:
+		e.w.WriteByte(jbIterator)
+		for _, item := range val {
+			if err := e.writeVal(item); err != nil {
+				return err
+			}
+		}
+		return e.w.WriteByte(jbEnd)
+	case []interface{}:
+		e.writeTag(jbArr, len(val))
+		for _, item := range val {
+			if err := e.writeVal(item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		return e.writeMap(val)
+	default:
+		return e.writeReflect(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+// writeReflect handles slices, maps and pointers of other types
+func (e *JavabinEncoder) writeReflect(rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return e.w.WriteByte(jbNull)
+		}
+		return e.writeVal(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		e.writeTag(jbArr, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			if err := e.writeVal(rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("Unsupported javabin map key type %s", rv.Type().Key())
+		}
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return e.writeMap(m)
+	case reflect.String:
+		e.writeStr(rv.String())
+		return nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		// named basic types
+		return e.writeVal(rv.Convert(basicTypes[rv.Kind()]).Interface())
+	}
+	return fmt.Errorf("Unsupported javabin type %s", rv.Type())
+}
+
+var basicTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+}
+
+func (e *JavabinEncoder) writeTag(tag byte, size int) {
+	if tag&0xe0 != 0 {
+		if size < 0x1f {
+			e.w.WriteByte(tag | byte(size))
+		} else {
+			e.w.WriteByte(tag | 0x1f)
+			e.writeVInt(uint64(size - 0x1f))
+		}
+		return
+	}
+	e.w.WriteByte(tag)
+	e.writeVInt(uint64(size))
+}
+
+func (e *JavabinEncoder) writeVInt(v uint64) {
+	for v&^0x7f != 0 {
+		e.w.WriteByte(byte(v&0x7f) | 0x80)
+		v >>= 7
+	}
+	e.w.WriteByte(byte(v))
+}
+
+func (e *JavabinEncoder) writeStr(s string) {
+	e.writeTag(jbStr, len(s))
+	e.w.WriteString(s)
+}
+
+func (e *JavabinEncoder) writeInt(v int32) {
+	if v > 0 {
+		b := jbSInt | byte(v&0x0f)
+		if v >= 0x0f {
+			e.w.WriteByte(b | 0x10)
+			e.writeVInt(uint64(uint32(v) >> 4))
+		} else {
+			e.w.WriteByte(b)
+		}
+		return
+	}
+	e.w.WriteByte(jbInt)
+	binary.Write(e.w, binary.BigEndian, v)
+}
+
+func (e *JavabinEncoder) writeLong(v int64) {
+	if uint64(v)&0xff00000000000000 == 0 {
+		b := jbSLong | byte(v&0x0f)
+		if v >= 0x0f {
+			e.w.WriteByte(b | 0x10)
+			e.writeVInt(uint64(v) >> 4)
+		} else {
+			e.w.WriteByte(b)
+		}
+		return
+	}
+	e.w.WriteByte(jbLong)
+	binary.Write(e.w, binary.BigEndian, v)
+}
+
+func (e *JavabinEncoder) writeFloat(v float32) {
+	e.w.WriteByte(jbFloat)
+	binary.Write(e.w, binary.BigEndian, math.Float32bits(v))
+}
+
+func (e *JavabinEncoder) writeExternString(s string) {
+	if idx, ok := e.externStrings[s]; ok {
+		e.writeTag(jbExternString, idx)
+		return
+	}
+	e.writeTag(jbExternString, 0)
+	e.writeStr(s)
+	if e.externStrings == nil {
+		e.externStrings = make(map[string]int)
+	}
+	e.externStrings[s] = len(e.externStrings) + 1
+}
+
+func (e *JavabinEncoder) writeNamedList(tag byte, nl NamedList) error {
+	e.writeTag(tag, len(nl))
+	for _, entry := range nl {
+		e.writeExternString(entry.Name)
+		if err := e.writeVal(entry.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (e *JavabinEncoder) writeMap(m map[string]interface{}) error {
+	e.writeTag(jbMap, len(m))
+	for _, k := range sortedKeys(m) {
+		e.writeExternString(k)
+		if err := e.writeVal(m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *JavabinEncoder) writeSolrDocument(doc SolrSearchDocument) error {
+	children := doc.Children()
+	fields := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		if k != "_childDocuments_" {
+			fields[k] = v
+		}
+	}
+	e.w.WriteByte(jbSolrDoc)
+	e.writeTag(jbOrderedMap, len(fields)+len(children))
+	for _, k := range sortedKeys(fields) {
+		e.writeExternString(k)
+		if err := e.writeVal(fields[k]); err != nil {
+			return err
+		}
+	}
+	for _, c := range children {
+		if err := e.writeSolrDocument(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *JavabinEncoder) writeSolrDocumentList(dl *JavabinDocList) error {
+	e.w.WriteByte(jbSolrDocList)
+	header := []interface{}{dl.NumFound, dl.Start, nil}
+	if dl.MaxScore != nil {
+		header[2] = *dl.MaxScore
+	}
+	if dl.NumFoundExact != nil {
+		header = append(header, *dl.NumFoundExact)
+	}
+	if err := e.writeVal(header); err != nil {
+		return err
+	}
+	e.writeTag(jbArr, len(dl.Docs))
+	for _, doc := range dl.Docs {
+		if err := e.writeSolrDocument(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *JavabinEncoder) writeSolrInputDocument(doc SolrDocument) error {
+	e.writeTag(jbSolrInputDoc, len(doc.fields)+len(doc.children))
+	// document boost, always 1
+	e.writeFloat(1)
+	for _, k := range sortedKeys(doc.fields) {
+		e.writeExternString(k)
+		v := doc.fields[k]
+		switch val := v.(type) {
+		case []SolrDocument:
+			// labelled child documents
+			e.writeTag(jbArr, len(val))
+			for _, c := range val {
+				if err := e.writeSolrInputDocument(c); err != nil {
+					return err
+				}
+			}
+			continue
+		case []string:
+			e.writeTag(jbArr, len(val))
+			for _, s := range val {
+				e.writeStr(s)
+			}
+			continue
+		}
+		if err := e.writeVal(v); err != nil {
+			return err
+		}
+	}
+	for _, c := range doc.children {
+		if err := e.writeSolrInputDocument(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetJavabin switches the client between JSON and Solr's binary javabin format. With javabin enabled Query
+// requests wt=javabin and PostDocs sends application/javabin bodies; other calls keep using JSON.
+// Date fields of javabin query results are returned as time.Time instead of strings.
+func (sc *SolrClient) SetJavabin(enabled bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.javabin = enabled
+}
+
+func (sc *SolrClient) usesJavabin() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.javabin
+}
+
+// WriteJavabin streams the doc collection to w as a javabin update request
+func (sdc *SolrDocumentCollection) WriteJavabin(w io.Writer) error {
+	docs := make(javabinIterator, 0, len(sdc.docs))
+	for _, doc := range sdc.docs {
+		docs = append(docs, doc)
+	}
+	// the layout of org.apache.solr.client.solrj.request.JavaBinUpdateRequestCodec
+	req := NamedList{
+		{Name: "params", Value: NamedList{}},
+		{Name: "delByQ", Value: nil},
+		{Name: "docs", Value: docs},
+	}
+	return NewJavabinEncoder(w).Encode(req)
+}
+
+// asNamedList returns v as a NamedList if it is a NamedList or SimpleOrderedMap
+func asNamedList(v interface{}) NamedList {
+	switch nl := v.(type) {
+	case NamedList:
+		return nl
+	case SimpleOrderedMap:
+		return NamedList(nl)
+	}
+	return nil
+}
+
+// getList returns the value of the first entry with a name if it is a NamedList or SimpleOrderedMap
+func (nl NamedList) getList(name string) NamedList {
+	v, _ := nl.Get(name)
+	return asNamedList(v)
+}
+
+// javabinStrings reads a request parameter, which is either a single string or an array of strings
+func javabinStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		s := make([]string, 0, len(val))
+		for _, item := range val {
+			s = append(s, fmt.Sprint(item))
+		}
+		return s
+	}
+	return nil
+}
+
+// javabinSearchResponse maps a decoded javabin query response onto a SolrSearchResponse
+func javabinSearchResponse(v interface{}) (*SolrSearchResponse, error) {
+	root := asNamedList(v)
+	if root == nil {
+		return nil, fmt.Errorf("Unexpected javabin response type %T", v)
+	}
+	var resp SolrSearchResponse
+
+	header := root.getList("responseHeader")
+	if zk, ok := header.Get("zkConnected"); ok {
+		resp.ResponseHeader.ZkConnected, _ = zk.(bool)
+	}
+	if status, ok := header.Get("status"); ok {
+		n, _ := toInt64(status)
+		resp.ResponseHeader.Status = int(n)
+	}
+	if qtime, ok := header.Get("QTime"); ok {
+		n, _ := toInt64(qtime)
+		resp.ResponseHeader.QTime = int(n)
+	}
+	params := &resp.ResponseHeader.Params
+	for _, p := range header.getList("params") {
+		vals := javabinStrings(p.Value)
+		if len(vals) == 0 {
+			continue
+		}
+		switch p.Name {
+		case "q":
+			params.Q = vals[0]
+		case "defType":
+			params.DefType = vals[0]
+		case "facet.field":
+			params.FacetField = vals
+		case "json.nl":
+			params.JSONNl = vals[0]
+		case "qf":
+			params.Qf = vals[0]
+		case "fl":
+			params.Fl = vals[0]
+		case "rows":
+			params.Rows = vals[0]
+		case "facet":
+			params.Facet = vals[0]
+		case "bq":
+			params.Bq = vals[0]
+		case "fq":
+			params.Fq = vals
+		case "sort":
+			params.Sort = vals[0]
+		case "start":
+			params.Start = vals[0]
+		}
+	}
+
+	if docList, ok := root.Get("response"); ok {
+		dl, ok := docList.(*JavabinDocList)
+		if !ok {
+			return nil, fmt.Errorf("Unexpected javabin document list type %T", docList)
+		}
+		resp.Response.NumFound = int(dl.NumFound)
+		resp.Response.Start = int(dl.Start)
+		if dl.MaxScore != nil {
+			resp.Response.MaxScore = float64(*dl.MaxScore)
+		}
+		resp.Response.Docs = dl.Docs
+	}
+
+	facetFields := root.getList("facet_counts").getList("facet_fields")
+	if len(facetFields) > 0 {
+		resp.FacetCounts.FacetFields = make(map[string][]SolrFacetField, len(facetFields))
+		for _, field := range facetFields {
+			counts := asNamedList(field.Value)
+			ff := make([]SolrFacetField, 0, len(counts))
+			for _, c := range counts {
+				n, _ := toInt64(c.Value)
+				ff = append(ff, SolrFacetField{Name: c.Name, Type: "int", Value: int(n)})
+			}
+			resp.FacetCounts.FacetFields[field.Name] = ff
+		}
+	}
+	return &resp, nil
+}
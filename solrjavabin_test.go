@@ -0,0 +1,264 @@
+package solrg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJavabinDecodeQueryResponse(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/query_response.javabin")
+	must(err)
+	val, err := UnmarshalJavabin(data)
+	must(err)
+	resp, err := javabinSearchResponse(val)
+	must(err)
+
+	if !resp.ResponseHeader.ZkConnected || resp.ResponseHeader.QTime != 3 {
+		t.Errorf("Unexpected response header %+v", resp.ResponseHeader)
+	}
+	params := resp.ResponseHeader.Params
+	if params.Q != "*:*" || len(params.Fq) != 2 || params.Fq[1] != "price_d:[* TO 100]" || params.FacetField[0] != "genre_s" {
+		t.Errorf("Unexpected params %+v", params)
+	}
+	if resp.Response.NumFound != 2 || resp.Response.MaxScore != 1 || len(resp.Response.Docs) != 2 {
+		t.Fatalf("Unexpected response %+v", resp.Response)
+	}
+
+	doc := resp.Response.Docs[0]
+	if doc.String("id") != "book-1" {
+		t.Errorf("Expected book-1, got %s", doc.String("id"))
+	}
+	if v, _ := doc.Version(); v != 1612345678901234567 {
+		t.Errorf("Unexpected version %d", v)
+	}
+	if n, _ := doc.Int64("rating_i"); n != -1 {
+		t.Errorf("Expected rating -1, got %d", n)
+	}
+	if published := doc["published_dt"].(time.Time); !published.Equal(time.Date(2015, 11, 4, 14, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date %s", published)
+	}
+	if children := doc.Children(); len(children) != 1 || children[0].String("id") != "book-1-review-1" {
+		t.Errorf("Unexpected children %v", children)
+	}
+
+	var books []struct {
+		ID        string    `solr:"id"`
+		Title     string    `solr:"title_t"`
+		Price     float64   `solr:"price_d"`
+		Pages     int       `solr:"pages_i"`
+		Published time.Time `solr:"published_dt"`
+		InStock   bool      `solr:"in_stock_b"`
+	}
+	must(resp.DecodeDocs(&books))
+	if books[0].Pages != 264 || books[0].Price != 39.99 || books[1].Title != "Solr in Action – 2nd edition" {
+		t.Errorf("Unexpected decoded docs %+v", books)
+	}
+
+	facets := resp.FacetCounts.FacetFields["genre_s"]
+	if len(facets) != 2 || facets[0].Name != "tech" || facets[0].Value != 2 || facets[1].Name != "fiction" {
+		t.Errorf("Unexpected facets %+v", facets)
+	}
+}
+
+func TestJavabinRoundTrip(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/query_response.javabin")
+	must(err)
+	val, err := UnmarshalJavabin(data)
+	must(err)
+	encoded, err := MarshalJavabin(val)
+	must(err)
+	again, err := UnmarshalJavabin(encoded)
+	must(err)
+	if !reflect.DeepEqual(val, again) {
+		t.Errorf("Round trip changed the response:\n%#v\n%#v", val, again)
+	}
+}
+
+func TestJavabinEncodeUpdateRequest(t *testing.T) {
+	expected, err := ioutil.ReadFile("testdata/update_request.javabin")
+	must(err)
+
+	doc := NewSolrDocument("1")
+	doc.Inc("popularity_i", 5)
+	doc.SetField("tags_ss", []string{"a", "b"})
+	doc.AddChild(NewSolrDocument("1-1"))
+	col := NewSolrDocumentCollection()
+	must(col.AddDoc(doc))
+
+	var buf bytes.Buffer
+	must(col.WriteJavabin(&buf))
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Unexpected update request\n% x\nexpected\n% x", buf.Bytes(), expected)
+	}
+
+	val, err := UnmarshalJavabin(expected)
+	must(err)
+	docs, _ := val.(NamedList).Get("docs")
+	decoded := docs.([]interface{})[0].(SolrDocument)
+	if decoded.ID() != "1" || len(decoded.Children()) != 1 || decoded.Children()[0].ID() != "1-1" {
+		t.Errorf("Unexpected decoded document %+v", decoded)
+	}
+	if inc, _ := decoded.Get("popularity_i"); inc.(map[string]interface{})["inc"] != int64(5) {
+		t.Errorf("Unexpected atomic update %v", inc)
+	}
+}
+
+func TestJavabinValues(t *testing.T) {
+	long := strings.Repeat("x", 1000)
+	values := []interface{}{
+		nil, true, false,
+		int8(-3), int16(-300),
+		int32(0), int32(14), int32(15), int32(1 << 20), int32(-1), int32(math.MaxInt32), int32(math.MinInt32),
+		int64(0), int64(15), int64(1 << 40), int64(-1), int64(math.MaxInt64), int64(math.MinInt64),
+		float32(1.5), 3.25, math.Inf(1),
+		"", "héllo", long,
+		[]byte{0, 1, 2, 255},
+		time.Date(2018, 7, 1, 12, 0, 0, 123e6, time.UTC),
+		time.Date(9999, 12, 31, 23, 59, 59, 999e6, time.UTC), time.Date(1000, 1, 1, 0, 0, 0, 1e6, time.UTC),
+		make([]interface{}, 40),
+		map[string]interface{}{"a": int64(1), "b": "two"},
+		NamedList{{"x", int32(1)}, {"x", int32(2)}, {long, long}},
+		SimpleOrderedMap{{"y", NamedList{}}},
+		JavabinEnumValue{Value: 2, Name: "HIGH"},
+		NamedListEntry{Name: "k", Value: "v"},
+	}
+	for _, v := range values {
+		data, err := MarshalJavabin(v)
+		must(err)
+		decoded, err := UnmarshalJavabin(data)
+		must(err)
+		if !reflect.DeepEqual(v, decoded) {
+			t.Errorf("Expected %#v, got %#v", v, decoded)
+		}
+	}
+
+	// Go ints are written as longs and other slices as arrays
+	data, err := MarshalJavabin([]string{"a", "b"})
+	must(err)
+	decoded, _ := UnmarshalJavabin(data)
+	if !reflect.DeepEqual(decoded, []interface{}{"a", "b"}) {
+		t.Errorf("Unexpected slice %#v", decoded)
+	}
+	data, _ = MarshalJavabin(42)
+	if decoded, _ := UnmarshalJavabin(data); decoded != int64(42) {
+		t.Errorf("Expected int64 42, got %#v", decoded)
+	}
+
+	if _, err := UnmarshalJavabin([]byte{1, 0}); err == nil {
+		t.Errorf("Expected an error for an unsupported version")
+	}
+	if _, err := UnmarshalJavabin([]byte{2, 0x25, 'a'}); err == nil {
+		t.Errorf("Expected an error for a truncated string")
+	}
+}
+
+func TestJavabinClient(t *testing.T) {
+	fixture, err := ioutil.ReadFile("testdata/query_response.javabin")
+	must(err)
+	var contentType string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/test/select":
+			if r.Form.Get("wt") != "javabin" {
+				t.Errorf("Expected wt=javabin, got %s", r.Form.Get("wt"))
+			}
+			w.Write(fixture)
+		case "/test/update":
+			if r.Form.Get("wt") != "json" {
+				t.Errorf("Expected a JSON response to be requested, got %s", r.Form.Get("wt"))
+			}
+			contentType = r.Header.Get("Content-Type")
+			body, _ = ioutil.ReadAll(r.Body)
+			w.Write([]byte(`{"responseHeader":{"status":0,"QTime":1}}`))
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+	sc.SetJavabin(true)
+
+	resp, err := sc.Query("test", "select", &SolrParams{Q: "*:*"}, time.Second)
+	must(err)
+	if resp.Response.NumFound != 2 {
+		t.Errorf("Expected 2 docs, got %d", resp.Response.NumFound)
+	}
+
+	col := NewSolrDocumentCollection()
+	must(col.AddDoc(NewSolrDocument("1")))
+	must(sc.PostDocs(&col, "test"))
+	if contentType != "application/javabin" {
+		t.Errorf("Expected application/javabin, got %s", contentType)
+	}
+	val, err := UnmarshalJavabin(body)
+	must(err)
+	if docs, _ := val.(NamedList).Get("docs"); len(docs.([]interface{})) != 1 {
+		t.Errorf("Unexpected update body %#v", val)
+	}
+}
+
+// benchResponse returns the same query response encoded as javabin and as JSON
+func benchResponse(n int) ([]byte, []byte) {
+	docs := make([]SolrSearchDocument, n)
+	for i := range docs {
+		docs[i] = SolrSearchDocument{
+			"id":        fmt.Sprintf("doc-%d", i),
+			"title_t":   []interface{}{"a reasonably long title for a benchmark document"},
+			"n_i":       int32(i),
+			"price_d":   float64(i) * 1.5,
+			"_version_": int64(1612345678901234567 + i),
+		}
+	}
+	jb, err := MarshalJavabin(SimpleOrderedMap{
+		{"responseHeader", SimpleOrderedMap{{"status", int32(0)}, {"QTime", int32(1)}}},
+		{"response", &JavabinDocList{NumFound: int64(n), Docs: docs}},
+	})
+	must(err)
+	var resp SolrSearchResponse
+	resp.Response.NumFound = n
+	resp.Response.Docs = docs
+	jsn, err := json.Marshal(resp)
+	must(err)
+	return jb, jsn
+}
+
+func BenchmarkJavabinDecodeResponse(b *testing.B) {
+	jb, _ := benchResponse(5000)
+	b.SetBytes(int64(len(jb)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		val, err := UnmarshalJavabin(jb)
+		must(err)
+		_, err = javabinSearchResponse(val)
+		must(err)
+	}
+}
+
+func BenchmarkJSONDecodeResponse(b *testing.B) {
+	_, jsn := benchResponse(5000)
+	b.SetBytes(int64(len(jsn)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var resp SolrSearchResponse
+		must(json.Unmarshal(jsn, &resp))
+	}
+}
+
+func BenchmarkWriteJavabin(b *testing.B) {
+	col := benchDocs(5000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		must(col.WriteJavabin(ioutil.Discard))
+	}
+}
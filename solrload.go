@@ -0,0 +1,128 @@
+package solrg
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+// defaultLoadTimeout is the default HTTP timeout of the Index* uploads. It is long because files are streamed
+// to Solr in a single request.
+const defaultLoadTimeout = time.Minute * 30
+
+// loadClient returns the HTTP client of an upload, with the default timeout if timeout is zero
+func loadClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = defaultLoadTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// CSVOptions hold the parameters of a CSV upload.
+// See https://lucene.apache.org/solr/guide/7_4/uploading-data-with-index-handlers.html#csv-formatted-index-updates
+type CSVOptions struct {
+	// Separator is the field separator. Defaults to ","
+	Separator string `url:"separator,omitempty"`
+	// Header indicates whether the first line contains field names. Defaults to true
+	Header *bool `url:"header,omitempty"`
+	// FieldNames are the field names to use when there is no header, or to override it
+	FieldNames []string `url:"fieldnames,omitempty,comma"`
+	// Skip lists fields that should not be indexed
+	Skip []string `url:"skip,omitempty,comma"`
+	// SkipLines is the number of lines to discard before the CSV data starts
+	SkipLines int `url:"skipLines,omitempty"`
+	// Split splits every field into multiple values using FieldSplit separators (or "," by default)
+	Split bool `url:"split,omitempty"`
+	// Encapsulator is the character used to encapsulate values. Defaults to '"'
+	Encapsulator string `url:"encapsulator,omitempty"`
+	// Escape is the character used to escape special characters
+	Escape string `url:"escape,omitempty"`
+	// Trim removes leading and trailing whitespace from values
+	Trim bool `url:"trim,omitempty"`
+	// KeepEmpty indexes empty values
+	KeepEmpty bool `url:"keepEmpty,omitempty"`
+	// RowID adds a field with this name containing the line number of each row
+	RowID string `url:"rowid,omitempty"`
+	// FieldSplit splits individual fields into multiple values, mapping a field name to the value separator
+	FieldSplit map[string]string `url:"-"`
+	// Literals adds a field with a fixed value to every document
+	Literals map[string]string `url:"-"`
+	// Timeout of the upload. Defaults to 30 minutes, raise it for big files as a timed out upload leaves the
+	// documents sent so far in the index.
+	Timeout time.Duration `url:"-"`
+}
+
+// JSONDocsOptions control how custom JSON is split into documents and mapped to fields by /update/json/docs.
+// See https://lucene.apache.org/solr/guide/7_4/transforming-and-indexing-custom-json.html
+type JSONDocsOptions struct {
+	// Split is the path at which the JSON is split into documents, e.g. "/exams"
+	Split string `url:"split,omitempty"`
+	// F are field mappings, e.g. "first:/first" or "/exams/subject"
+	F []string `url:"f,omitempty"`
+	// SrcField stores the original JSON of each document in this field
+	SrcField string `url:"srcField,omitempty"`
+	// MapUniqueKeyOnly only maps the uniqueKey field and indexes the rest of the JSON in Df
+	MapUniqueKeyOnly bool `url:"mapUniqueKeyOnly,omitempty"`
+	// Df is the field that receives the JSON when MapUniqueKeyOnly is set
+	Df string `url:"df,omitempty"`
+	// Echo returns the documents Solr would index instead of indexing them
+	Echo bool `url:"echo,omitempty"`
+	// Timeout of the upload. Defaults to 30 minutes.
+	Timeout time.Duration `url:"-"`
+}
+
+// IndexCSV streams CSV data from r into a collection
+func (sc *SolrClient) IndexCSV(collection string, r io.Reader, csvOpts CSVOptions, opts ...UpdateOption) (*UpdateResponse, error) {
+	params, err := query.Values(csvOpts)
+	if err != nil {
+		return nil, err
+	}
+	for field, sep := range csvOpts.FieldSplit {
+		params.Set("f."+field+".split", "true")
+		params.Set("f."+field+".separator", sep)
+	}
+	for field, val := range csvOpts.Literals {
+		params.Set("literal."+field, val)
+	}
+	for _, opt := range opts {
+		opt(params)
+	}
+	return sc.postUpdateHandler(loadClient(csvOpts.Timeout), collection, "update", "application/csv", params, r)
+}
+
+// IndexJSONLines streams newline delimited JSON documents from r into a collection. The upload times out
+// after 30 minutes, use IndexJSONLinesWithTimeout for bigger files.
+func (sc *SolrClient) IndexJSONLines(collection string, r io.Reader, opts ...UpdateOption) (*UpdateResponse, error) {
+	return sc.IndexJSONLinesWithTimeout(collection, r, 0, opts...)
+}
+
+// IndexJSONLinesWithTimeout is IndexJSONLines with a custom timeout
+func (sc *SolrClient) IndexJSONLinesWithTimeout(collection string, r io.Reader, timeout time.Duration, opts ...UpdateOption) (*UpdateResponse, error) {
+	return sc.postUpdateHandler(loadClient(timeout), collection, "update/json/docs", "application/json", updateParams(opts), r)
+}
+
+// IndexXML streams a Solr XML update message (<add><doc>...</doc></add>) from r into a collection. The upload
+// times out after 30 minutes, use IndexXMLWithTimeout for bigger files.
+func (sc *SolrClient) IndexXML(collection string, r io.Reader, opts ...UpdateOption) (*UpdateResponse, error) {
+	return sc.IndexXMLWithTimeout(collection, r, 0, opts...)
+}
+
+// IndexXMLWithTimeout is IndexXML with a custom timeout
+func (sc *SolrClient) IndexXMLWithTimeout(collection string, r io.Reader, timeout time.Duration, opts ...UpdateOption) (*UpdateResponse, error) {
+	return sc.postUpdateHandler(loadClient(timeout), collection, "update", "application/xml", updateParams(opts), r)
+}
+
+// IndexJSONDocs streams custom (non Solr formatted) JSON from r into a collection, using jsonOpts to
+// split it into documents and map it to fields
+func (sc *SolrClient) IndexJSONDocs(collection string, r io.Reader, jsonOpts JSONDocsOptions, opts ...UpdateOption) (*UpdateResponse, error) {
+	params, err := query.Values(jsonOpts)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(params)
+	}
+	return sc.postUpdateHandler(loadClient(jsonOpts.Timeout), collection, "update/json/docs", "application/json", params, r)
+}
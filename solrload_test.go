@@ -0,0 +1,97 @@
+package solrg
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexCSVAndJSONLines(t *testing.T) {
+	var path, contentType, body string
+	var params map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		path, contentType, body, params = r.URL.Path, r.Header.Get("Content-Type"), string(b), r.URL.Query()
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	csv := "1;a|b\n2;c\n"
+	_, err = sc.IndexCSV("test", strings.NewReader(csv), CSVOptions{
+		Separator:  ";",
+		Header:     Bool(false),
+		FieldNames: []string{"id", "tags_ss"},
+		FieldSplit: map[string]string{"tags_ss": "|"},
+		Literals:   map[string]string{"source_s": "import"},
+	}, CommitWithin(time.Second))
+	must(err)
+
+	if path != "/test/update" || contentType != "application/csv" || body != csv {
+		t.Errorf("Unexpected CSV request: %s %s %q", path, contentType, body)
+	}
+	expected := map[string]string{
+		"separator":           ";",
+		"header":              "false",
+		"fieldnames":          "id,tags_ss",
+		"f.tags_ss.split":     "true",
+		"f.tags_ss.separator": "|",
+		"literal.source_s":    "import",
+		"commitWithin":        "1000",
+	}
+	for k, v := range expected {
+		if len(params[k]) != 1 || params[k][0] != v {
+			t.Errorf("Expected param %s=%s, got %v", k, v, params[k])
+		}
+	}
+
+	jsonl := "{\"id\":\"1\"}\n{\"id\":\"2\"}\n"
+	_, err = sc.IndexJSONLines("test", strings.NewReader(jsonl))
+	must(err)
+	if path != "/test/update/json/docs" || contentType != "application/json" || body != jsonl {
+		t.Errorf("Unexpected JSON Lines request: %s %s %q", path, contentType, body)
+	}
+
+	xml := `<add><doc><field name="id">1</field></doc></add>`
+	_, err = sc.IndexXML("test", strings.NewReader(xml), CommitWithin(time.Second))
+	must(err)
+	if path != "/test/update" || contentType != "application/xml" || body != xml || params["commitWithin"][0] != "1000" {
+		t.Errorf("Unexpected XML request: %s %s %q %v", path, contentType, body, params)
+	}
+
+	_, err = sc.IndexJSONDocs("test", strings.NewReader(`{"exams":[]}`), JSONDocsOptions{Split: "/exams", F: []string{"first:/first", "/exams/subject"}})
+	must(err)
+	if params["split"][0] != "/exams" || len(params["f"]) != 2 {
+		t.Errorf("Unexpected custom JSON params %v", params)
+	}
+}
+
+func TestIndexTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	if _, err = sc.IndexCSV("test", strings.NewReader("1\n"), CSVOptions{Timeout: 50 * time.Millisecond}); err == nil {
+		t.Error("Expected the CSV upload to time out")
+	}
+	if _, err = sc.IndexJSONDocs("test", strings.NewReader("{}"), JSONDocsOptions{Timeout: 50 * time.Millisecond}); err == nil {
+		t.Error("Expected the custom JSON upload to time out")
+	}
+	if _, err = sc.IndexJSONLinesWithTimeout("test", strings.NewReader("{}\n"), 50*time.Millisecond); err == nil {
+		t.Error("Expected the JSON Lines upload to time out")
+	}
+	if _, err = sc.IndexXMLWithTimeout("test", strings.NewReader("<add/>"), 50*time.Millisecond); err == nil {
+		t.Error("Expected the XML upload to time out")
+	}
+	if _, err = sc.IndexXMLWithTimeout("test", strings.NewReader("<add/>"), time.Second); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+}
@@ -0,0 +1,524 @@
+package solrg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SolrDateFormat is the layout Solr expects for date fields. Times are converted to UTC before formatting.
+const SolrDateFormat = "2006-01-02T15:04:05.999Z"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	solrDocumentType  = reflect.TypeOf(SolrDocument{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	structFieldCache  sync.Map
+)
+
+// solrStructField describes how a single struct field maps to a Solr field.
+// Fields are configured with a `solr:"name,omitempty,multi"` tag. When no solr
+// tag is present the json tag name is used, and then the Go field name.
+// Schema options in the tag are used by SchemaFromStruct.
+type solrStructField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	multi     bool
+	options   []string
+}
+
+// FormatSolrDate formats a time.Time using the Solr date format
+func FormatSolrDate(t time.Time) string {
+	return t.UTC().Format(SolrDateFormat)
+}
+
+// ParseSolrDate parses a date returned by Solr
+func ParseSolrDate(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// cachedStructFields returns the Solr field mapping for a struct type
+func cachedStructFields(t reflect.Type) []solrStructField {
+	if f, ok := structFieldCache.Load(t); ok {
+		return f.([]solrStructField)
+	}
+	f, _ := structFieldCache.LoadOrStore(t, typeSolrFields(t, nil))
+	return f.([]solrStructField)
+}
+
+func typeSolrFields(t reflect.Type, parent []int) []solrStructField {
+	var fields []solrStructField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+
+		tag, tagged := sf.Tag.Lookup("solr")
+		if !tagged {
+			tag = sf.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+
+		// flatten embedded structs that haven't been given an explicit name
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				fields = append(fields, typeSolrFields(ft, index)...)
+				continue
+			}
+		}
+		if sf.PkgPath != "" {
+			// unexported
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		f := solrStructField{name: name, index: index, options: parts[1:]}
+		for _, o := range parts[1:] {
+			switch o {
+			case "omitempty":
+				f.omitEmpty = true
+			case "multi":
+				f.multi = true
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// fieldByIndex walks an index path, returning false if it passes through a nil embedded pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// fieldByIndexAlloc walks an index path, allocating nil embedded pointers along the way
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
+
+// MarshalSolrDoc converts a tagged struct (or pointer to one) into a map of Solr field values.
+// time.Time values are formatted as Solr dates, slices become multivalued fields and nested
+// structs become labelled child documents.
+func MarshalSolrDoc(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("Cannot marshal a nil value to a Solr document")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct || rv.Type() == timeType {
+		return nil, fmt.Errorf("Cannot marshal %s to a Solr document, expected a struct", rv.Type())
+	}
+	return marshalSolrStruct(rv)
+}
+
+func marshalSolrStruct(rv reflect.Value) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+	for _, f := range cachedStructFields(rv.Type()) {
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok {
+			continue
+		}
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		val, err := solrValue(fv)
+		if err != nil {
+			return nil, fmt.Errorf("Error marshalling field %s: %s", f.name, err)
+		}
+		if val == nil {
+			continue
+		}
+		if _, isSlice := val.([]interface{}); f.multi && !isSlice {
+			val = []interface{}{val}
+		}
+		doc[f.name] = val
+	}
+	return doc, nil
+}
+
+// solrValue converts a reflected Go value into a value that serializes to the right Solr JSON type
+func solrValue(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Type() == timeType {
+		return FormatSolrDate(v.Interface().(time.Time)), nil
+	}
+	if v.Type() == solrDocumentType {
+		doc := v.Interface().(SolrDocument)
+		return doc.solrMap()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return solrValue(v.Elem())
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return v.Interface(), nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return marshalSolrStruct(v)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("Unsupported map key type %s", v.Type().Key())
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			mv, err := solrValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			m[iter.Key().String()] = mv
+		}
+		return m, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return nil, nil
+			}
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				return v.Interface(), nil
+			}
+		}
+		vals := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			ev, err := solrValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if ev != nil {
+				vals = append(vals, ev)
+			}
+		}
+		return vals, nil
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v.Interface(), nil
+	}
+	return nil, fmt.Errorf("Unsupported type %s", v.Type())
+}
+
+// toSolrDoc converts an item passed to PostStructs into something that marshals to a Solr document
+func toSolrDoc(item interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(item)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct && rv.Type() != timeType {
+		return marshalSolrStruct(rv)
+	}
+	return item, nil
+}
+
+// UnmarshalSolrDoc copies the fields of a document returned by Solr into a tagged struct.
+// v must be a pointer to a struct.
+func UnmarshalSolrDoc(doc map[string]interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("UnmarshalSolrDoc requires a non-nil pointer, got %T", v)
+	}
+	return assignSolrValue(rv.Elem(), doc)
+}
+
+// Decode copies the fields of the document into a tagged struct. v must be a pointer to a struct.
+func (sd SolrSearchDocument) Decode(v interface{}) error {
+	return UnmarshalSolrDoc(sd, v)
+}
+
+// DecodeDocs copies the returned documents into a slice of tagged structs. v must be a pointer to a slice.
+func (r *SolrSearchResponse) DecodeDocs(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("DecodeDocs requires a pointer to a slice, got %T", v)
+	}
+	docs := make([]interface{}, len(r.Response.Docs))
+	for i, d := range r.Response.Docs {
+		docs[i] = map[string]interface{}(d)
+	}
+	return assignSolrValue(rv.Elem(), docs)
+}
+
+func unmarshalSolrStruct(rv reflect.Value, doc map[string]interface{}) error {
+	for _, f := range cachedStructFields(rv.Type()) {
+		val, ok := doc[f.name]
+		if !ok {
+			continue
+		}
+		fv := fieldByIndexAlloc(rv, f.index)
+		if err := assignSolrValue(fv, val); err != nil {
+			return fmt.Errorf("Error decoding field %s: %s", f.name, err)
+		}
+	}
+	return nil
+}
+
+// assignSolrValue sets dst from a decoded Solr value
+func assignSolrValue(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		return nil
+	}
+	if sd, ok := src.(SolrSearchDocument); ok {
+		src = map[string]interface{}(sd)
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assignSolrValue(dst.Elem(), src)
+	case reflect.Interface:
+		if dst.NumMethod() == 0 {
+			dst.Set(reflect.ValueOf(src))
+			return nil
+		}
+	}
+
+	if dst.Type() == timeType {
+		switch s := src.(type) {
+		case time.Time:
+			dst.Set(reflect.ValueOf(s))
+			return nil
+		case string:
+			t, err := ParseSolrDate(s)
+			if err != nil {
+				return err
+			}
+			dst.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+
+	srcSlice, srcIsSlice := src.([]interface{})
+
+	switch dst.Kind() {
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			switch s := src.(type) {
+			case []byte:
+				dst.SetBytes(s)
+				return nil
+			case string:
+				b, err := base64.StdEncoding.DecodeString(s)
+				if err != nil {
+					return err
+				}
+				dst.SetBytes(b)
+				return nil
+			}
+		}
+		if !srcIsSlice {
+			srcSlice = []interface{}{src}
+		}
+		out := reflect.MakeSlice(dst.Type(), len(srcSlice), len(srcSlice))
+		for i, e := range srcSlice {
+			if err := assignSolrValue(out.Index(i), e); err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+		return nil
+	case reflect.Array:
+		if !srcIsSlice {
+			srcSlice = []interface{}{src}
+		}
+		if len(srcSlice) > dst.Len() {
+			return fmt.Errorf("Cannot fit %d values into %s", len(srcSlice), dst.Type())
+		}
+		for i, e := range srcSlice {
+			if err := assignSolrValue(dst.Index(i), e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// single valued destination, unwrap single element multivalued fields
+	if srcIsSlice {
+		switch len(srcSlice) {
+		case 0:
+			return nil
+		case 1:
+			return assignSolrValue(dst, srcSlice[0])
+		default:
+			return fmt.Errorf("Cannot assign %d values to single valued %s", len(srcSlice), dst.Type())
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		m, ok := src.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Cannot assign %T to %s", src, dst.Type())
+		}
+		return unmarshalSolrStruct(dst, m)
+	case reflect.Map:
+		m, ok := src.(map[string]interface{})
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("Cannot assign %T to %s", src, dst.Type())
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), len(m)))
+		}
+		for k, e := range m {
+			ev := reflect.New(dst.Type().Elem()).Elem()
+			if err := assignSolrValue(ev, e); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), ev)
+		}
+		return nil
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return fmt.Errorf("Cannot assign %T to %s", src, dst.Type())
+		}
+		dst.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return fmt.Errorf("Cannot assign %T to %s", src, dst.Type())
+		}
+		dst.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := toInt64(src)
+		if !ok || dst.OverflowInt(n) {
+			return fmt.Errorf("Cannot assign %v to %s", src, dst.Type())
+		}
+		dst.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := toInt64(src)
+		if !ok || n < 0 || dst.OverflowUint(uint64(n)) {
+			return fmt.Errorf("Cannot assign %v to %s", src, dst.Type())
+		}
+		dst.SetUint(uint64(n))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat64(src)
+		if !ok || dst.OverflowFloat(f) {
+			return fmt.Errorf("Cannot assign %v to %s", src, dst.Type())
+		}
+		dst.SetFloat(f)
+		return nil
+	}
+	return fmt.Errorf("Unsupported destination type %s", dst.Type())
+}
+
+// toInt64 converts any decoded numeric value to an int64
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint:
+		return int64(n), uint64(n) <= math.MaxInt64
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	case float32:
+		return int64(n), float32(int64(n)) == n
+	case float64:
+		return int64(n), float64(int64(n)) == n
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// toFloat64 converts any decoded numeric value to a float64
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	if i, ok := toInt64(v); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
@@ -0,0 +1,121 @@
+package solrg
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testAuthor struct {
+	ID   string `solr:"id"`
+	Name string `solr:"name_s"`
+}
+
+type testBook struct {
+	ID        string       `solr:"id"`
+	Title     string       `solr:"title_t"`
+	Pages     int          `solr:"pages_i"`
+	Price     float64      `solr:"price_d,omitempty"`
+	InStock   bool         `solr:"in_stock_b"`
+	Published time.Time    `solr:"published_dt"`
+	Tags      []string     `solr:"tags_ss"`
+	Genre     string       `solr:"genre_ss,multi"`
+	Author    *testAuthor  `solr:"author"`
+	Reviews   []testAuthor `solr:"reviews,omitempty"`
+	Legacy    string       `json:"legacy_s"`
+	Ignored   string       `solr:"-"`
+}
+
+func TestMarshalSolrDoc(t *testing.T) {
+	book := testBook{
+		ID:        "1",
+		Title:     "Go in Action",
+		Pages:     264,
+		InStock:   true,
+		Published: time.Date(2015, 11, 4, 9, 30, 0, 0, time.FixedZone("EST", -5*3600)),
+		Tags:      []string{"go", "programming"},
+		Genre:     "tech",
+		Author:    &testAuthor{ID: "1-a", Name: "Bill"},
+		Legacy:    "old",
+		Ignored:   "nope",
+	}
+
+	doc, err := MarshalSolrDoc(&book)
+	must(err)
+
+	jsn, err := json.Marshal(doc)
+	must(err)
+	var got map[string]interface{}
+	must(json.Unmarshal(jsn, &got))
+
+	expected := map[string]interface{}{
+		"id":           "1",
+		"title_t":      "Go in Action",
+		"pages_i":      float64(264),
+		"in_stock_b":   true,
+		"published_dt": "2015-11-04T14:30:00Z",
+		"tags_ss":      []interface{}{"go", "programming"},
+		"genre_ss":     []interface{}{"tech"},
+		"author":       map[string]interface{}{"id": "1-a", "name_s": "Bill"},
+		"legacy_s":     "old",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected Solr doc.\nexpected: %v\ngot:      %v", expected, got)
+	}
+}
+
+func TestUnmarshalSolrDoc(t *testing.T) {
+	var doc SolrSearchDocument
+	must(json.Unmarshal([]byte(`{
+		"id": "1",
+		"title_t": ["Go in Action"],
+		"pages_i": 264,
+		"price_d": 39.99,
+		"in_stock_b": true,
+		"published_dt": "2015-11-04T14:30:00Z",
+		"tags_ss": ["go", "programming"],
+		"genre_ss": "tech",
+		"author": {"id": "1-a", "name_s": "Bill"},
+		"reviews": [{"id": "1-r1", "name_s": "Ann"}],
+		"_version_": 1612345678901234567
+	}`), &doc))
+
+	var book testBook
+	must(doc.Decode(&book))
+
+	if book.Title != "Go in Action" || book.Pages != 264 || book.Price != 39.99 || !book.InStock {
+		t.Errorf("Scalar fields were not decoded correctly: %+v", book)
+	}
+	if !book.Published.Equal(time.Date(2015, 11, 4, 14, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected published date %s", book.Published)
+	}
+	if !reflect.DeepEqual(book.Tags, []string{"go", "programming"}) || book.Genre != "tech" {
+		t.Errorf("Multivalued fields were not decoded correctly: %v %v", book.Tags, book.Genre)
+	}
+	if book.Author == nil || book.Author.Name != "Bill" {
+		t.Errorf("Nested author was not decoded: %v", book.Author)
+	}
+	if len(book.Reviews) != 1 || book.Reviews[0].Name != "Ann" {
+		t.Errorf("Nested reviews were not decoded: %v", book.Reviews)
+	}
+
+	// multiple values can't be squeezed into a single valued field
+	doc["title_t"] = []interface{}{"a", "b"}
+	if err := doc.Decode(&book); err == nil {
+		t.Error("Expected an error decoding 2 values into a string field")
+	}
+
+	// unsigned values, e.g. set by hand, decode as long as they fit
+	doc["title_t"] = "Go in Action"
+	doc["pages_i"] = uint64(300)
+	must(doc.Decode(&book))
+	if book.Pages != 300 {
+		t.Errorf("Unexpected pages %d", book.Pages)
+	}
+	doc["pages_i"] = uint64(math.MaxUint64)
+	if err := doc.Decode(&book); err == nil {
+		t.Error("Expected an error decoding an overflowing uint64")
+	}
+}
@@ -0,0 +1,103 @@
+package solrg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AddChild adds an anonymous child document, indexed under _childDocuments_
+func (sd *SolrDocument) AddChild(child SolrDocument) {
+	sd.children = append(sd.children, child)
+}
+
+// AddNestedChild adds a child document under a labelled nested field (e.g. "comments")
+func (sd *SolrDocument) AddNestedChild(field string, child SolrDocument) {
+	existing, _ := sd.fields[field].([]SolrDocument)
+	sd.fields[field] = append(existing, child)
+}
+
+// Children returns the anonymous child documents
+func (sd *SolrDocument) Children() []SolrDocument {
+	return sd.children
+}
+
+// NestedChildren returns the child documents held under a labelled nested field
+func (sd *SolrDocument) NestedChildren(field string) []SolrDocument {
+	children, _ := sd.fields[field].([]SolrDocument)
+	return children
+}
+
+// allChildren returns both anonymous and labelled child documents
+func (sd *SolrDocument) allChildren() []SolrDocument {
+	children := append([]SolrDocument{}, sd.children...)
+	for _, v := range sd.fields {
+		if nested, ok := v.([]SolrDocument); ok {
+			children = append(children, nested...)
+		}
+	}
+	return children
+}
+
+// Children returns the anonymous child documents of a returned document (_childDocuments_)
+func (sd SolrSearchDocument) Children() []SolrSearchDocument {
+	return sd.ChildrenOf("_childDocuments_")
+}
+
+// ChildrenOf returns the child documents held under a labelled nested field of a returned document.
+// A single nested child object is returned as a one element slice.
+func (sd SolrSearchDocument) ChildrenOf(field string) []SolrSearchDocument {
+	switch v := sd[field].(type) {
+	case map[string]interface{}:
+		return []SolrSearchDocument{SolrSearchDocument(v)}
+	case SolrSearchDocument:
+		return []SolrSearchDocument{v}
+	case []SolrSearchDocument:
+		return v
+	case []interface{}:
+		var children []SolrSearchDocument
+		for _, c := range v {
+			switch cd := c.(type) {
+			case map[string]interface{}:
+				children = append(children, SolrSearchDocument(cd))
+			case SolrSearchDocument:
+				children = append(children, cd)
+			}
+		}
+		return children
+	}
+	return nil
+}
+
+// localParam quotes a value for use inside local params, e.g. {!parent which="..."}
+func localParam(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// ParentQuery returns a block join query matching parents of the children matched by childQuery.
+// allParents must match all parent documents, e.g. "type_s:book".
+func ParentQuery(allParents string, childQuery string) string {
+	return fmt.Sprintf("{!parent which=%s}%s", localParam(allParents), childQuery)
+}
+
+// ChildQuery returns a block join query matching children of the parents matched by parentQuery.
+// allParents must match all parent documents, e.g. "type_s:book".
+func ChildQuery(allParents string, parentQuery string) string {
+	return fmt.Sprintf("{!child of=%s}%s", localParam(allParents), parentQuery)
+}
+
+// ChildTransformer returns a [child] doc transformer for use in fl, which returns matching children
+// nested in each parent. Empty filters and a limit <= 0 are omitted.
+func ChildTransformer(parentFilter string, childFilter string, limit int) string {
+	params := []string{"child"}
+	if parentFilter != "" {
+		params = append(params, "parentFilter="+localParam(parentFilter))
+	}
+	if childFilter != "" {
+		params = append(params, "childFilter="+localParam(childFilter))
+	}
+	if limit > 0 {
+		params = append(params, fmt.Sprintf("limit=%d", limit))
+	}
+	return "[" + strings.Join(params, " ") + "]"
+}
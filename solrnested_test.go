@@ -0,0 +1,78 @@
+package solrg
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNestedSolrDocJSON(t *testing.T) {
+	book := NewSolrDocument("book-1")
+	book.SetField("type_s", []string{"book"})
+
+	review := NewSolrDocument("review-1")
+	review.SetInt("stars_i", 5)
+	book.AddChild(review)
+
+	comment := NewSolrDocument("comment-1")
+	comment.SetField("text_t", []string{"nice"})
+	book.AddNestedChild("comments", comment)
+
+	jsn, err := book.SolrJSON()
+	must(err)
+	var got map[string]interface{}
+	must(json.Unmarshal([]byte(jsn), &got))
+
+	expected := map[string]interface{}{
+		"id":     "book-1",
+		"type_s": []interface{}{"book"},
+		"_childDocuments_": []interface{}{
+			map[string]interface{}{"id": "review-1", "stars_i": float64(5)},
+		},
+		"comments": []interface{}{
+			map[string]interface{}{"id": "comment-1", "text_t": []interface{}{"nice"}},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected json.\nexpected: %v\ngot:      %v", expected, got)
+	}
+
+	// children need ids too
+	book.AddChild(SolrDocument{fields: map[string]interface{}{}})
+	docs := NewSolrDocumentCollection()
+	if err := docs.AddDoc(book); err == nil {
+		t.Error("Expected an error adding a doc with a child that has no id")
+	}
+}
+
+func TestNestedSolrSearchDocument(t *testing.T) {
+	var doc SolrSearchDocument
+	must(json.Unmarshal([]byte(`{
+		"id": "book-1",
+		"_childDocuments_": [{"id": "review-1", "_childDocuments_": [{"id": "reply-1"}]}],
+		"author": {"id": "author-1"}
+	}`), &doc))
+
+	children := doc.Children()
+	if len(children) != 1 || children[0].String("id") != "review-1" {
+		t.Fatalf("Unexpected children %v", children)
+	}
+	if grandChildren := children[0].Children(); len(grandChildren) != 1 || grandChildren[0].String("id") != "reply-1" {
+		t.Errorf("Unexpected grandchildren %v", grandChildren)
+	}
+	if author := doc.ChildrenOf("author"); len(author) != 1 || author[0].String("id") != "author-1" {
+		t.Errorf("Unexpected labelled child %v", author)
+	}
+}
+
+func TestBlockJoinQueries(t *testing.T) {
+	if q := ParentQuery("type_s:book", "stars_i:5"); q != `{!parent which="type_s:book"}stars_i:5` {
+		t.Errorf("Unexpected parent query %s", q)
+	}
+	if q := ChildQuery(`title_t:"go lang"`, "id:book-1"); q != `{!child of="title_t:\"go lang\""}id:book-1` {
+		t.Errorf("Unexpected child query %s", q)
+	}
+	if fl := ChildTransformer("type_s:book", "stars_i:[4 TO *]", 10); fl != `[child parentFilter="type_s:book" childFilter="stars_i:[4 TO *]" limit=10]` {
+		t.Errorf("Unexpected child transformer %s", fl)
+	}
+}
@@ -64,7 +64,7 @@ func (sd SolrSearchDocument) String(fieldName string) string {
 
 // Float64 returns a float64 field
 func (sd SolrSearchDocument) Float64(fieldName string) (float64, error) {
-	f, ok := sd[fieldName].(float64)
+	f, ok := toFloat64(sd[fieldName])
 	if ok {
 		return f, nil
 	}
@@ -73,11 +73,13 @@ func (sd SolrSearchDocument) Float64(fieldName string) (float64, error) {
 
 // Int64 returns a int64 field or casts a float64 field to an int
 func (sd SolrSearchDocument) Int64(fieldName string) (int64, error) {
-	f, ok := sd[fieldName].(float64)
-	if !ok {
-		return 0, fmt.Errorf("Unable to assert float64 for field %s", fieldName)
+	if f, ok := sd[fieldName].(float64); ok {
+		return int64(f), nil
+	}
+	if i, ok := toInt64(sd[fieldName]); ok {
+		return i, nil
 	}
-	return int64(f), nil
+	return 0, fmt.Errorf("Unable to assert int64 for field %s", fieldName)
 }
 
 // Slice returns a slice (array) field
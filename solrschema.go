@@ -1,31 +1,355 @@
 package solrg
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
 )
 
+// FieldTypes returns the field types of a collection's schema
 func (sc *SolrClient) FieldTypes(collection string) (*FieldTypesResponse, error) {
+	var fieldTypeResp FieldTypesResponse
+	if err := sc.getSchema(collection, "fieldtypes", &fieldTypeResp); err != nil {
+		return nil, err
+	}
+	return &fieldTypeResp, nil
+}
 
-	url := "http://" + sc.LBNodeAddress() + "/" + collection + "/schema/fieldtypes"
-	req, err := http.NewRequest("GET", url, nil)
-	var client = &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
+// FieldType returns a single field type of a collection's schema
+func (sc *SolrClient) FieldType(collection string, name string) (*FieldType, error) {
+	var resp struct {
+		FieldType FieldType `json:"fieldType"`
+	}
+	if err := sc.getSchema(collection, "fieldtypes/"+url.PathEscape(name), &resp); err != nil {
 		return nil, err
 	}
+	return &resp.FieldType, nil
+}
+
+// Fields returns the fields of a collection's schema
+func (sc *SolrClient) Fields(collection string) ([]SchemaField, error) {
+	var resp struct {
+		Fields []SchemaField `json:"fields"`
+	}
+	if err := sc.getSchema(collection, "fields", &resp); err != nil {
+		return nil, err
+	}
+	return resp.Fields, nil
+}
+
+// Field returns a single field of a collection's schema. IsNotFound reports whether the field doesn't exist.
+func (sc *SolrClient) Field(collection string, name string) (*SchemaField, error) {
+	var resp struct {
+		Field SchemaField `json:"field"`
+	}
+	if err := sc.getSchema(collection, "fields/"+url.PathEscape(name), &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Field, nil
+}
+
+// DynamicFields returns the dynamic fields of a collection's schema
+func (sc *SolrClient) DynamicFields(collection string) ([]SchemaField, error) {
+	var resp struct {
+		DynamicFields []SchemaField `json:"dynamicFields"`
+	}
+	if err := sc.getSchema(collection, "dynamicfields", &resp); err != nil {
+		return nil, err
+	}
+	return resp.DynamicFields, nil
+}
+
+// CopyFields returns the copy field rules of a collection's schema
+func (sc *SolrClient) CopyFields(collection string) ([]CopyField, error) {
+	var resp struct {
+		CopyFields []CopyField `json:"copyFields"`
+	}
+	if err := sc.getSchema(collection, "copyfields", &resp); err != nil {
+		return nil, err
+	}
+	return resp.CopyFields, nil
+}
+
+// UniqueKey returns the name of the unique key field of a collection's schema
+func (sc *SolrClient) UniqueKey(collection string) (string, error) {
+	var resp struct {
+		UniqueKey string `json:"uniqueKey"`
+	}
+	if err := sc.getSchema(collection, "uniquekey", &resp); err != nil {
+		return "", err
+	}
+	return resp.UniqueKey, nil
+}
+
+// getSchema reads a resource of the Schema API into v
+func (sc *SolrClient) getSchema(collection string, path string, v interface{}) error {
+	url := "http://" + sc.LBNodeAddress() + "/" + collection + "/schema/" + path
+	var client = &http.Client{
+		Timeout: time.Second * 10,
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return readSolrError(resp)
+	}
 
-	var fieldTypeResp FieldTypesResponse
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, v)
+}
+
+// SchemaField is a field or dynamic field definition. Unset properties are inherited from the field type.
+// See https://lucene.apache.org/solr/guide/7_4/defining-fields.html
+type SchemaField struct {
+	Name                     string `json:"name"`
+	Type                     string `json:"type"`
+	Default                  string `json:"default,omitempty"`
+	Indexed                  *bool  `json:"indexed,omitempty"`
+	Stored                   *bool  `json:"stored,omitempty"`
+	DocValues                *bool  `json:"docValues,omitempty"`
+	SortMissingFirst         *bool  `json:"sortMissingFirst,omitempty"`
+	SortMissingLast          *bool  `json:"sortMissingLast,omitempty"`
+	MultiValued              *bool  `json:"multiValued,omitempty"`
+	Uninvertible             *bool  `json:"uninvertible,omitempty"`
+	OmitNorms                *bool  `json:"omitNorms,omitempty"`
+	OmitTermFreqAndPositions *bool  `json:"omitTermFreqAndPositions,omitempty"`
+	OmitPositions            *bool  `json:"omitPositions,omitempty"`
+	TermVectors              *bool  `json:"termVectors,omitempty"`
+	TermPositions            *bool  `json:"termPositions,omitempty"`
+	TermOffsets              *bool  `json:"termOffsets,omitempty"`
+	TermPayloads             *bool  `json:"termPayloads,omitempty"`
+	Required                 *bool  `json:"required,omitempty"`
+	UseDocValuesAsStored     *bool  `json:"useDocValuesAsStored,omitempty"`
+	Large                    *bool  `json:"large,omitempty"`
+}
+
+// CopyField copies the values of Source to Dest at index time. Source and Dest may use wildcards.
+type CopyField struct {
+	Source   string `json:"source"`
+	Dest     string `json:"dest"`
+	MaxChars int    `json:"maxChars,omitempty"`
+}
+
+// SchemaRequest combines Schema API commands into a single request. Solr applies all of the
+// commands or, if any of them fails, none.
+// See https://lucene.apache.org/solr/guide/7_4/schema-api.html#modify-the-schema
+type SchemaRequest struct {
+	commands []updateCommand
+}
+
+// NewSchemaRequest returns a new, empty SchemaRequest
+func NewSchemaRequest() *SchemaRequest {
+	return &SchemaRequest{}
+}
+
+func (sr *SchemaRequest) add(name string, value interface{}) {
+	sr.commands = append(sr.commands, updateCommand{name, value})
+}
+
+// AddField adds a new field
+func (sr *SchemaRequest) AddField(field SchemaField) {
+	sr.add("add-field", field)
+}
+
+// ReplaceField replaces the definition of an existing field
+func (sr *SchemaRequest) ReplaceField(field SchemaField) {
+	sr.add("replace-field", field)
+}
+
+// DeleteField deletes a field
+func (sr *SchemaRequest) DeleteField(name string) {
+	sr.add("delete-field", map[string]string{"name": name})
+}
+
+// AddDynamicField adds a new dynamic field, named with a leading or trailing wildcard, e.g. *_s
+func (sr *SchemaRequest) AddDynamicField(field SchemaField) {
+	sr.add("add-dynamic-field", field)
+}
+
+// ReplaceDynamicField replaces the definition of an existing dynamic field
+func (sr *SchemaRequest) ReplaceDynamicField(field SchemaField) {
+	sr.add("replace-dynamic-field", field)
+}
+
+// DeleteDynamicField deletes a dynamic field
+func (sr *SchemaRequest) DeleteDynamicField(name string) {
+	sr.add("delete-dynamic-field", map[string]string{"name": name})
+}
+
+// AddCopyField adds a copy field rule
+func (sr *SchemaRequest) AddCopyField(copyField CopyField) {
+	sr.add("add-copy-field", copyField)
+}
+
+// DeleteCopyField deletes a copy field rule
+func (sr *SchemaRequest) DeleteCopyField(source string, dest string) {
+	sr.add("delete-copy-field", CopyField{Source: source, Dest: dest})
+}
+
+// AddFieldType adds a new field type
+func (sr *SchemaRequest) AddFieldType(fieldType FieldType) {
+	sr.add("add-field-type", fieldType)
+}
+
+// ReplaceFieldType replaces the definition of an existing field type
+func (sr *SchemaRequest) ReplaceFieldType(fieldType FieldType) {
+	sr.add("replace-field-type", fieldType)
+}
+
+// DeleteFieldType deletes a field type
+func (sr *SchemaRequest) DeleteFieldType(name string) {
+	sr.add("delete-field-type", map[string]string{"name": name})
+}
+
+// NumCommands returns the number of commands in the request
+func (sr *SchemaRequest) NumCommands() int {
+	return len(sr.commands)
+}
+
+// WriteJSON writes the request to w as a JSON command object. Like update requests, the Schema API
+// allows repeated keys, which is how multiple commands are combined.
+func (sr *SchemaRequest) WriteJSON(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	bw.WriteByte('{')
+	for i, cmd := range sr.commands {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteString(`"` + cmd.name + `":`)
+		if err := enc.Encode(cmd.value); err != nil {
+			return err
+		}
+	}
+	bw.WriteByte('}')
+	return bw.Flush()
+}
+
+// SchemaResponse holds Solr's response to a schema update
+type SchemaResponse struct {
+	ResponseHeader struct {
+		Status int `json:"status"`
+		QTime  int `json:"QTime"`
+	} `json:"responseHeader"`
+}
+
+// SchemaCommandError describes why a single command of a SchemaRequest failed
+type SchemaCommandError struct {
+	// Command is the failed command as it was sent, e.g. {"add-field": {...}}
+	Command       map[string]interface{}
+	ErrorMessages []string
+}
+
+// SchemaError is returned when Solr rejects a SchemaRequest. None of the commands were applied.
+type SchemaError struct {
+	Errors []SchemaCommandError
+	Err    *SolrError
+}
+
+func (e *SchemaError) Error() string {
+	var msgs []string
+	for _, ce := range e.Errors {
+		msgs = append(msgs, ce.ErrorMessages...)
+	}
+	return fmt.Sprintf("Error updating schema: %s: %s", e.Err.Msg, strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the underlying SolrError
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// newSchemaError reads the per command errors of a failed schema update. Solr 7 reports them in
+// error.details, older versions in a top level errors list.
+func newSchemaError(se *SolrError) error {
+	var body struct {
+		Errors []map[string]interface{} `json:"errors"`
+		Error  struct {
+			Details []map[string]interface{} `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(se.Body, &body) != nil {
+		return se
+	}
+	details := append(body.Error.Details, body.Errors...)
+	if len(details) == 0 {
+		return se
+	}
+
+	e := &SchemaError{Err: se}
+	for _, d := range details {
+		ce := SchemaCommandError{Command: make(map[string]interface{})}
+		for k, v := range d {
+			if k != "errorMessages" {
+				ce.Command[k] = v
+				continue
+			}
+			switch msgs := v.(type) {
+			case string:
+				ce.ErrorMessages = append(ce.ErrorMessages, strings.TrimSpace(msgs))
+			case []interface{}:
+				for _, m := range msgs {
+					ce.ErrorMessages = append(ce.ErrorMessages, strings.TrimSpace(fmt.Sprint(m)))
+				}
+			}
+		}
+		e.Errors = append(e.Errors, ce)
+	}
+	return e
+}
+
+// UpdateSchema sends all of the commands of a SchemaRequest to the Schema API in one call.
+// If Solr rejects any of them a *SchemaError is returned.
+func (sc *SolrClient) UpdateSchema(collection string, req *SchemaRequest) (*SchemaResponse, error) {
+	var body bytes.Buffer
+	if err := req.WriteJSON(&body); err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(buf, &fieldTypeResp)
+	url := "http://" + sc.LBNodeAddress() + "/" + collection + "/schema"
+	var client = &http.Client{
+		Timeout: time.Second * 30,
+	}
+	resp, err := client.Post(url, "application/json", &body)
 	if err != nil {
 		return nil, err
 	}
-	return &fieldTypeResp, nil
+	defer resp.Body.Close()
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, newSchemaError(newSolrError(resp, buf))
+	}
+
+	var schemaResp SchemaResponse
+	if err := json.Unmarshal(buf, &schemaResp); err != nil {
+		return nil, fmt.Errorf("Error parsing schema response: %s", err)
+	}
+	// Solr versions before 7 report command errors with a 200 status
+	var withErrors struct {
+		Errors []interface{} `json:"errors"`
+	}
+	if json.Unmarshal(buf, &withErrors) == nil && len(withErrors.Errors) > 0 {
+		return nil, newSchemaError(newSolrError(resp, buf))
+	}
+	return &schemaResp, nil
 }
 
 type FieldTypesResponse struct {
@@ -33,41 +357,257 @@ type FieldTypesResponse struct {
 		Status int `json:"status"`
 		QTime  int `json:"QTime"`
 	} `json:"responseHeader"`
-	FieldTypes []struct {
-		Name          string `json:"name"`
-		Class         string `json:"class"`
-		IndexAnalyzer struct {
-			Tokenizer struct {
-				Class string `json:"class"`
-			} `json:"tokenizer"`
-		} `json:"indexAnalyzer,omitempty"`
-		QueryAnalyzer struct {
-			Tokenizer struct {
-				Class     string `json:"class"`
-				Delimiter string `json:"delimiter"`
-			} `json:"tokenizer"`
-		} `json:"queryAnalyzer,omitempty"`
-		SortMissingLast bool `json:"sortMissingLast,omitempty"`
-		MultiValued     bool `json:"multiValued,omitempty"`
-		Indexed         bool `json:"indexed,omitempty"`
-		Stored          bool `json:"stored,omitempty"`
-		Analyzer        struct {
-			Tokenizer struct {
-				Class string `json:"class"`
-			} `json:"tokenizer"`
-			Filters []struct {
-				Class   string `json:"class"`
-				Encoder string `json:"encoder"`
-			} `json:"filters"`
-		} `json:"analyzer,omitempty"`
-		DocValues                 bool   `json:"docValues,omitempty"`
-		Geo                       string `json:"geo,omitempty"`
-		MaxDistErr                string `json:"maxDistErr,omitempty"`
-		DistErrPct                string `json:"distErrPct,omitempty"`
-		DistanceUnits             string `json:"distanceUnits,omitempty"`
-		PositionIncrementGap      string `json:"positionIncrementGap,omitempty"`
-		SubFieldSuffix            string `json:"subFieldSuffix,omitempty"`
-		Dimension                 string `json:"dimension,omitempty"`
-		AutoGeneratePhraseQueries string `json:"autoGeneratePhraseQueries,omitempty"`
-	} `json:"fieldTypes"`
+	FieldTypes []FieldType `json:"fieldTypes"`
+}
+
+// FieldType is a field type definition of the schema. Attributes without a dedicated field, e.g. precisionStep
+// or the options of custom field type classes, are kept in Extra so a FieldType read from Solr can be sent back
+// unchanged with ReplaceFieldType. Properties left nil use the defaults of the field type class.
+// See https://lucene.apache.org/solr/guide/7_4/field-type-definitions-and-properties.html
+type FieldType struct {
+	Name              string      `json:"name"`
+	Class             string      `json:"class"`
+	Analyzer          *Analyzer   `json:"analyzer,omitempty"`
+	IndexAnalyzer     *Analyzer   `json:"indexAnalyzer,omitempty"`
+	QueryAnalyzer     *Analyzer   `json:"queryAnalyzer,omitempty"`
+	MultiTermAnalyzer *Analyzer   `json:"multiTermAnalyzer,omitempty"`
+	Similarity        *Similarity `json:"similarity,omitempty"`
+
+	PositionIncrementGap      string `json:"positionIncrementGap,omitempty"`
+	AutoGeneratePhraseQueries string `json:"autoGeneratePhraseQueries,omitempty"`
+	SynonymQueryStyle         string `json:"synonymQueryStyle,omitempty"`
+	EnableGraphQueries        string `json:"enableGraphQueries,omitempty"`
+	DocValuesFormat           string `json:"docValuesFormat,omitempty"`
+	PostingsFormat            string `json:"postingsFormat,omitempty"`
+	Geo                       string `json:"geo,omitempty"`
+	MaxDistErr                string `json:"maxDistErr,omitempty"`
+	DistErrPct                string `json:"distErrPct,omitempty"`
+	DistanceUnits             string `json:"distanceUnits,omitempty"`
+	SubFieldSuffix            string `json:"subFieldSuffix,omitempty"`
+	Dimension                 string `json:"dimension,omitempty"`
+
+	Indexed                  *bool `json:"indexed,omitempty"`
+	Stored                   *bool `json:"stored,omitempty"`
+	DocValues                *bool `json:"docValues,omitempty"`
+	SortMissingFirst         *bool `json:"sortMissingFirst,omitempty"`
+	SortMissingLast          *bool `json:"sortMissingLast,omitempty"`
+	MultiValued              *bool `json:"multiValued,omitempty"`
+	Uninvertible             *bool `json:"uninvertible,omitempty"`
+	OmitNorms                *bool `json:"omitNorms,omitempty"`
+	OmitTermFreqAndPositions *bool `json:"omitTermFreqAndPositions,omitempty"`
+	OmitPositions            *bool `json:"omitPositions,omitempty"`
+	TermVectors              *bool `json:"termVectors,omitempty"`
+	TermPositions            *bool `json:"termPositions,omitempty"`
+	TermOffsets              *bool `json:"termOffsets,omitempty"`
+	TermPayloads             *bool `json:"termPayloads,omitempty"`
+	Required                 *bool `json:"required,omitempty"`
+	UseDocValuesAsStored     *bool `json:"useDocValuesAsStored,omitempty"`
+	Large                    *bool `json:"large,omitempty"`
+
+	// Extra holds all other attributes of the field type
+	Extra map[string]interface{} `json:"-"`
+}
+
+// fieldTypeJSON has the fields of FieldType without its json methods
+type fieldTypeJSON FieldType
+
+var fieldTypeKeys = jsonFieldNames(reflect.TypeOf(fieldTypeJSON{}))
+
+// UnmarshalJSON reads a field type, keeping unknown attributes in Extra
+func (ft *FieldType) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*fieldTypeJSON)(ft)); err != nil {
+		return err
+	}
+	extra, err := unknownJSONKeys(data, fieldTypeKeys)
+	ft.Extra = extra
+	return err
+}
+
+// MarshalJSON writes a field type including the attributes in Extra
+func (ft FieldType) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(fieldTypeJSON(ft))
+	if err != nil {
+		return nil, err
+	}
+	return appendJSONKeys(data, ft.Extra)
+}
+
+// Analyzer is the analysis chain of a text field type: char filters, a tokenizer and token filters.
+// Alternatively Class names a complete Lucene Analyzer implementation.
+// See https://lucene.apache.org/solr/guide/7_4/analyzers.html
+type Analyzer struct {
+	Class       string              `json:"class,omitempty"`
+	CharFilters []AnalyzerComponent `json:"charFilters,omitempty"`
+	Tokenizer   *AnalyzerComponent  `json:"tokenizer,omitempty"`
+	Filters     []AnalyzerComponent `json:"filters,omitempty"`
+}
+
+// AnalyzerComponent is a char filter, tokenizer or token filter of an Analyzer. It is identified by the Class of
+// its factory, e.g. solr.StopFilterFactory, or by Name, e.g. stop. Attributes holds its configuration.
+type AnalyzerComponent struct {
+	Class      string
+	Name       string
+	Attributes map[string]string
+}
+
+// UnmarshalJSON reads a component, keeping all attributes other than class and name in Attributes
+func (ac *AnalyzerComponent) UnmarshalJSON(data []byte) error {
+	var err error
+	ac.Class, ac.Name, ac.Attributes, err = unmarshalComponent(data)
+	return err
+}
+
+// MarshalJSON writes a component as a flat object of its class, name and attributes
+func (ac AnalyzerComponent) MarshalJSON() ([]byte, error) {
+	return marshalComponent(ac.Class, ac.Name, ac.Attributes)
+}
+
+// Similarity is the similarity implementation used to score documents matching a field type
+type Similarity struct {
+	Class      string
+	Attributes map[string]string
+}
+
+// UnmarshalJSON reads a similarity, keeping all attributes other than class in Attributes
+func (s *Similarity) UnmarshalJSON(data []byte) error {
+	var name string
+	var err error
+	s.Class, name, s.Attributes, err = unmarshalComponent(data)
+	if name != "" {
+		if s.Attributes == nil {
+			s.Attributes = make(map[string]string)
+		}
+		s.Attributes["name"] = name
+	}
+	return err
+}
+
+// MarshalJSON writes a similarity as a flat object of its class and attributes
+func (s Similarity) MarshalJSON() ([]byte, error) {
+	return marshalComponent(s.Class, "", s.Attributes)
+}
+
+// unmarshalComponent reads a flat object of a class, a name and attributes. Solr returns attribute
+// values as strings, other scalars are converted so they compare equal to the values Solr returns.
+func unmarshalComponent(data []byte) (string, string, map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return "", "", nil, err
+	}
+	var class, name string
+	var attrs map[string]string
+	for k, v := range m {
+		var s string
+		switch val := v.(type) {
+		case string:
+			s = val
+		case json.Number, bool:
+			s = fmt.Sprint(val)
+		case nil:
+			continue
+		default:
+			b, err := json.Marshal(val)
+			if err != nil {
+				return "", "", nil, err
+			}
+			s = string(b)
+		}
+		switch k {
+		case "class":
+			class = s
+		case "name":
+			name = s
+		default:
+			if attrs == nil {
+				attrs = make(map[string]string)
+			}
+			attrs[k] = s
+		}
+	}
+	return class, name, attrs, nil
+}
+
+// marshalComponent writes a flat object of a class, a name and attributes
+func marshalComponent(class string, name string, attrs map[string]string) ([]byte, error) {
+	var first []byte
+	var err error
+	switch {
+	case class != "" && name != "":
+		first, err = json.Marshal(struct {
+			Class string `json:"class"`
+			Name  string `json:"name"`
+		}{class, name})
+	case name != "":
+		first, err = json.Marshal(map[string]string{"name": name})
+	default:
+		first, err = json.Marshal(map[string]string{"class": class})
+	}
+	if err != nil {
+		return nil, err
+	}
+	extra := make(map[string]interface{}, len(attrs))
+	for k, v := range attrs {
+		extra[k] = v
+	}
+	return appendJSONKeys(first, extra)
+}
+
+// jsonFieldNames returns the json names of the fields of a struct type
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// unknownJSONKeys returns the members of a JSON object that are not in known
+func unknownJSONKeys(data []byte, known map[string]bool) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	for k := range m {
+		if known[k] {
+			delete(m, k)
+		}
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// appendJSONKeys adds the members of extra, in sorted order, to the end of a JSON object
+func appendJSONKeys(data []byte, extra map[string]interface{}) ([]byte, error) {
+	if len(extra) == 0 {
+		return data, nil
+	}
+	buf := bytes.NewBuffer(bytes.TrimSuffix(bytes.TrimSpace(data), []byte("}")))
+	for _, k := range sortedKeys(extra) {
+		v, err := json.Marshal(extra[k])
+		if err != nil {
+			return nil, err
+		}
+		key, _ := json.Marshal(k)
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
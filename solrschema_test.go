@@ -1,7 +1,15 @@
 package solrg
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -17,7 +25,162 @@ func TestSolrFieldTypes(t *testing.T) {
 
 	//list field types
 	for _, v := range r.FieldTypes {
-		fmt.Printf("\tname: %s, analyzer: %s, all\n", v.Name, v.Analyzer)
+		fmt.Printf("\tname: %s, analyzer: %v, all\n", v.Name, v.Analyzer)
 	}
 
 }
+
+func TestSchemaRequestJSON(t *testing.T) {
+	req := NewSchemaRequest()
+	req.AddField(SchemaField{Name: "title_en", Type: "text_en", Stored: Bool(true), MultiValued: Bool(false)})
+	req.AddField(SchemaField{Name: "body_en", Type: "text_en"})
+	req.DeleteField("old")
+	req.AddCopyField(CopyField{Source: "title_en", Dest: "_text_"})
+	req.DeleteCopyField("body_en", "_text_")
+	req.AddFieldType(FieldType{Name: "text_ws", Class: "solr.TextField", Analyzer: &Analyzer{
+		Tokenizer: &AnalyzerComponent{Class: "solr.WhitespaceTokenizerFactory"},
+	}})
+
+	var buf bytes.Buffer
+	must(req.WriteJSON(&buf))
+	expected := `{"add-field":{"name":"title_en","type":"text_en","stored":true,"multiValued":false}
+,"add-field":{"name":"body_en","type":"text_en"}
+,"delete-field":{"name":"old"}
+,"add-copy-field":{"source":"title_en","dest":"_text_"}
+,"delete-copy-field":{"source":"body_en","dest":"_text_"}
+,"add-field-type":{"name":"text_ws","class":"solr.TextField","analyzer":{"tokenizer":{"class":"solr.WhitespaceTokenizerFactory"}}}
+}`
+	if buf.String() != expected {
+		t.Errorf("Unexpected schema request:\n%s", buf.String())
+	}
+	if req.NumCommands() != 6 {
+		t.Errorf("Expected 6 commands, got %d", req.NumCommands())
+	}
+}
+
+func TestSchemaAPI(t *testing.T) {
+	var posted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/test/schema/fields":
+			fmt.Fprint(w, `{"fields":[{"name":"id","type":"string","indexed":true,"stored":true,"required":true},{"name":"title","type":"text_general"}]}`)
+		case "/test/schema/fields/missing":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"msg":"No such path /schema/fields/missing","code":404}}`)
+		case "/test/schema/dynamicfields":
+			fmt.Fprint(w, `{"dynamicFields":[{"name":"*_s","type":"string","multiValued":false}]}`)
+		case "/test/schema/copyfields":
+			fmt.Fprint(w, `{"copyFields":[{"source":"title","dest":"_text_","maxChars":256}]}`)
+		case "/test/schema":
+			b, _ := ioutil.ReadAll(r.Body)
+			posted = string(b)
+			if strings.Contains(posted, "bad_type") {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"responseHeader":{"status":400,"QTime":1},"error":{"metadata":["error-class","org.apache.solr.api.ApiBag$ExceptionWithErrObject"],"details":[{"add-field":{"name":"x","type":"bad_type"},"errorMessages":["Field 'x': Field type 'bad_type' not found.\n"]}],"msg":"error processing commands","code":400}}`)
+				return
+			}
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":12}}`)
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	fields, err := sc.Fields("test")
+	must(err)
+	if len(fields) != 2 || !*fields[0].Required || fields[1].Stored != nil {
+		t.Errorf("Unexpected fields %+v", fields)
+	}
+	_, err = sc.Field("test", "missing")
+	if !IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	dynamic, err := sc.DynamicFields("test")
+	must(err)
+	if len(dynamic) != 1 || dynamic[0].Name != "*_s" || *dynamic[0].MultiValued {
+		t.Errorf("Unexpected dynamic fields %+v", dynamic)
+	}
+	copyFields, err := sc.CopyFields("test")
+	must(err)
+	if len(copyFields) != 1 || copyFields[0].MaxChars != 256 {
+		t.Errorf("Unexpected copy fields %+v", copyFields)
+	}
+
+	req := NewSchemaRequest()
+	req.AddField(SchemaField{Name: "x", Type: "string"})
+	resp, err := sc.UpdateSchema("test", req)
+	must(err)
+	if resp.ResponseHeader.QTime != 12 || !strings.HasPrefix(posted, `{"add-field":`) {
+		t.Errorf("Unexpected response %+v for %s", resp, posted)
+	}
+
+	req = NewSchemaRequest()
+	req.AddField(SchemaField{Name: "x", Type: "bad_type"})
+	_, err = sc.UpdateSchema("test", req)
+	var se *SchemaError
+	if !errors.As(err, &se) || !IsBadRequest(err) {
+		t.Fatalf("Expected a *SchemaError, got %T: %v", err, err)
+	}
+	if len(se.Errors) != 1 || se.Errors[0].ErrorMessages[0] != "Field 'x': Field type 'bad_type' not found." || se.Errors[0].Command["add-field"] == nil {
+		t.Errorf("Unexpected command errors %+v", se.Errors)
+	}
+}
+
+func TestFieldTypeRoundTrip(t *testing.T) {
+	raw := `{
+  "name":"text_en_custom",
+  "class":"solr.TextField",
+  "positionIncrementGap":"100",
+  "omitNorms":false,
+  "multiValued":true,
+  "precisionStep":"0",
+  "customOption":{"nested":[1,2]},
+  "indexAnalyzer":{
+    "charFilters":[{"class":"solr.HTMLStripCharFilterFactory"},{"class":"solr.MappingCharFilterFactory","mapping":"mapping-ISOLatin1Accent.txt"}],
+    "tokenizer":{"class":"solr.StandardTokenizerFactory","maxTokenLength":"255"},
+    "filters":[
+      {"class":"solr.StopFilterFactory","words":"lang/stopwords_en.txt","ignoreCase":"true"},
+      {"name":"lowercase"},
+      {"class":"solr.SynonymGraphFilterFactory","synonyms":"synonyms.txt","expand":true,"ignoreCase":"true"}]},
+  "queryAnalyzer":{"tokenizer":{"class":"solr.StandardTokenizerFactory"}},
+  "multiTermAnalyzer":{"tokenizer":{"class":"solr.KeywordTokenizerFactory"},"filters":[{"class":"solr.LowerCaseFilterFactory"}]},
+  "similarity":{"class":"solr.BM25SimilarityFactory","k1":"1.2","b":"0.75"}}`
+
+	var ft FieldType
+	must(json.Unmarshal([]byte(raw), &ft))
+	if ft.OmitNorms == nil || *ft.OmitNorms || !*ft.MultiValued || ft.Stored != nil {
+		t.Errorf("Unexpected properties %+v", ft)
+	}
+	if ft.Extra["precisionStep"] != "0" || ft.Extra["customOption"] == nil {
+		t.Errorf("Unexpected extra attributes %v", ft.Extra)
+	}
+	idx := ft.IndexAnalyzer
+	if len(idx.CharFilters) != 2 || idx.CharFilters[1].Attributes["mapping"] != "mapping-ISOLatin1Accent.txt" {
+		t.Errorf("Unexpected char filters %+v", idx.CharFilters)
+	}
+	if idx.Tokenizer.Attributes["maxTokenLength"] != "255" || idx.Filters[1].Name != "lowercase" || idx.Filters[2].Attributes["expand"] != "true" {
+		t.Errorf("Unexpected analyzer %+v", idx)
+	}
+	if ft.MultiTermAnalyzer.Tokenizer.Class != "solr.KeywordTokenizerFactory" || ft.Similarity.Attributes["k1"] != "1.2" {
+		t.Errorf("Unexpected multi term analyzer or similarity %+v %+v", ft.MultiTermAnalyzer, ft.Similarity)
+	}
+
+	// writing it back keeps every attribute; scalar attributes become strings like Solr returns them
+	out, err := json.Marshal(ft)
+	must(err)
+	var got, expected interface{}
+	must(json.Unmarshal(out, &got))
+	must(json.Unmarshal([]byte(strings.Replace(raw, `"expand":true`, `"expand":"true"`, 1)), &expected))
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Round trip changed the field type:\n%s", out)
+	}
+
+	req := NewSchemaRequest()
+	req.ReplaceFieldType(ft)
+	var buf bytes.Buffer
+	must(req.WriteJSON(&buf))
+	if !strings.Contains(buf.String(), `"precisionStep":"0"`) || !strings.Contains(buf.String(), `"charFilters":[`) {
+		t.Errorf("Unexpected replace-field-type command %s", buf.String())
+	}
+}
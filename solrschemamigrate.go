@@ -0,0 +1,373 @@
+package solrg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SchemaDefinition describes the desired schema of a collection. It can be written as a Go value or loaded from
+// a JSON or YAML file with the same layout as the Schema API (fieldTypes, fields, dynamicFields and copyFields).
+type SchemaDefinition struct {
+	FieldTypes    []FieldType   `json:"fieldTypes,omitempty"`
+	Fields        []SchemaField `json:"fields,omitempty"`
+	DynamicFields []SchemaField `json:"dynamicFields,omitempty"`
+	CopyFields    []CopyField   `json:"copyFields,omitempty"`
+	// UniqueKey is the unique key field of a live schema, set by Schema. The Schema API can't change it, so
+	// definitions leave it out.
+	UniqueKey string `json:"-"`
+}
+
+// ReadSchemaDefinition reads a JSON schema definition
+func ReadSchemaDefinition(r io.Reader) (*SchemaDefinition, error) {
+	var def SchemaDefinition
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&def); err != nil {
+		return nil, fmt.Errorf("Error reading schema definition: %s", err)
+	}
+	return &def, nil
+}
+
+// ReadSchemaDefinitionYAML reads a YAML schema definition. Keys are the same as in JSON definitions.
+func ReadSchemaDefinitionYAML(r io.Reader) (*SchemaDefinition, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("Error reading schema definition: %s", err)
+	}
+	// decode through JSON so both formats share the field names and unknown key checks
+	jsn, err := json.Marshal(yamlToJSON(v))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading schema definition: %s", err)
+	}
+	return ReadSchemaDefinition(bytes.NewReader(jsn))
+}
+
+// yamlToJSON converts the map[interface{}]interface{} values yaml.v2 decodes mappings into to maps with string
+// keys, which encoding/json can marshal
+func yamlToJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = yamlToJSON(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = yamlToJSON(val)
+		}
+	}
+	return v
+}
+
+// ReadSchemaDefinitionFile reads a schema definition from a file, as YAML if it has a .yaml or .yml extension
+// and as JSON otherwise
+func ReadSchemaDefinitionFile(path string) (*SchemaDefinition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ReadSchemaDefinitionYAML(f)
+	}
+	return ReadSchemaDefinition(f)
+}
+
+// Schema fetches the field types, fields, dynamic fields, copy fields and unique key of a collection's schema
+func (sc *SolrClient) Schema(collection string) (*SchemaDefinition, error) {
+	return sc.schema(collection, false)
+}
+
+// schema fetches a collection's schema. With showDefaults fields and field types list every property,
+// including the ones inherited from their type or class.
+func (sc *SolrClient) schema(collection string, showDefaults bool) (*SchemaDefinition, error) {
+	var schema SchemaDefinition
+	params := ""
+	if showDefaults {
+		params = "?showDefaults=true"
+	}
+	// each resource sets its own member of the definition
+	for _, path := range []string{"fieldtypes", "fields", "dynamicfields", "copyfields"} {
+		if err := sc.getSchema(collection, path+params, &schema); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if schema.UniqueKey, err = sc.UniqueKey(collection); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// SchemaChange is a single step of a SchemaPlan
+type SchemaChange struct {
+	// Command is the Schema API command, e.g. add-field or delete-copy-field
+	Command string
+	// Name is the name of the field or field type, or "source -> dest" for copy fields
+	Name string
+	// Current is the live definition, nil for adds
+	Current interface{}
+	// Desired is the new definition, nil for deletes
+	Desired interface{}
+}
+
+// SchemaPlan lists the changes needed to migrate a live schema to a SchemaDefinition, in the order they are applied
+type SchemaPlan struct {
+	Changes []SchemaChange
+}
+
+// Empty reports whether the live schema already matches the definition
+func (p *SchemaPlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String returns a readable summary of the plan, one change per line
+func (p *SchemaPlan) String() string {
+	if p.Empty() {
+		return "No schema changes"
+	}
+	var sb strings.Builder
+	for _, c := range p.Changes {
+		switch {
+		case c.Current == nil:
+			sb.WriteString("+ ")
+		case c.Desired == nil:
+			sb.WriteString("- ")
+		default:
+			sb.WriteString("~ ")
+		}
+		sb.WriteString(c.Command + " " + c.Name)
+		if c.Desired != nil {
+			if b, err := json.Marshal(c.Desired); err == nil {
+				sb.WriteString(" " + string(b))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Request returns a SchemaRequest applying all of the changes
+func (p *SchemaPlan) Request() *SchemaRequest {
+	req := NewSchemaRequest()
+	for _, c := range p.Changes {
+		value := c.Desired
+		if value == nil {
+			switch cur := c.Current.(type) {
+			case CopyField:
+				value = CopyField{Source: cur.Source, Dest: cur.Dest}
+			default:
+				value = map[string]string{"name": c.Name}
+			}
+		}
+		req.add(c.Command, value)
+	}
+	return req
+}
+
+// SchemaMigrationOptions controls MigrateSchema
+type SchemaMigrationOptions struct {
+	// DryRun computes the plan without changing the schema
+	DryRun bool
+	// Prune deletes field types, fields, dynamic fields and copy fields that are not in the definition.
+	// Solr's internal fields, named with leading and trailing underscores like _version_, the unique key field
+	// and field types still used by a field are never deleted.
+	Prune bool
+}
+
+// MigrateSchema compares the live schema of a collection to a definition and applies the differences as a
+// single Schema API request. Properties left unset in the definition are not compared, so a definition only
+// needs to list what it cares about. The live schema is read with showDefaults, so a property a field
+// inherits from its type matches the same value in the definition. The plan is returned even if applying
+// it fails.
+func (sc *SolrClient) MigrateSchema(collection string, desired *SchemaDefinition, opts SchemaMigrationOptions) (*SchemaPlan, error) {
+	live, err := sc.schema(collection, true)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := DiffSchema(live, desired, opts.Prune)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun || plan.Empty() {
+		return plan, nil
+	}
+	_, err = sc.UpdateSchema(collection, plan.Request())
+	return plan, err
+}
+
+// DiffSchema computes the plan that migrates the live schema to the desired one. See MigrateSchema.
+func DiffSchema(live *SchemaDefinition, desired *SchemaDefinition, prune bool) (*SchemaPlan, error) {
+	plan := &SchemaPlan{}
+
+	copyKey := func(cf CopyField) string { return cf.Source + " -> " + cf.Dest }
+	liveCopies := make(map[string]CopyField)
+	for _, cf := range live.CopyFields {
+		liveCopies[copyKey(cf)] = cf
+	}
+	desiredCopies := make(map[string]bool)
+	var addCopies []SchemaChange
+	for _, cf := range desired.CopyFields {
+		key := copyKey(cf)
+		desiredCopies[key] = true
+		cur, exists := liveCopies[key]
+		if exists && cur.MaxChars == cf.MaxChars {
+			continue
+		}
+		if exists {
+			// copy fields can't be replaced, only deleted and added again
+			plan.Changes = append(plan.Changes, SchemaChange{Command: "delete-copy-field", Name: key, Current: cur})
+		}
+		addCopies = append(addCopies, SchemaChange{Command: "add-copy-field", Name: key, Desired: cf})
+	}
+	if prune {
+		for _, cf := range live.CopyFields {
+			if key := copyKey(cf); !desiredCopies[key] {
+				plan.Changes = append(plan.Changes, SchemaChange{Command: "delete-copy-field", Name: key, Current: cf})
+			}
+		}
+	}
+
+	liveTypes := make(map[string]interface{})
+	for _, ft := range live.FieldTypes {
+		liveTypes[ft.Name] = ft
+	}
+	desiredTypes := make([]namedDefinition, len(desired.FieldTypes))
+	for i, ft := range desired.FieldTypes {
+		desiredTypes[i] = namedDefinition{ft.Name, ft}
+	}
+	typeDeletes, err := diffNamed(plan, "field-type", liveTypes, desiredTypes, prune, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var deletes []SchemaChange
+	// field types still used by a remaining field can't be deleted
+	usedTypes := make(map[string]bool)
+	for _, defs := range []struct {
+		kind    string
+		live    []SchemaField
+		desired []SchemaField
+		keep    string
+	}{
+		{"field", live.Fields, desired.Fields, live.UniqueKey},
+		{"dynamic-field", live.DynamicFields, desired.DynamicFields, ""},
+	} {
+		liveFields := make(map[string]interface{})
+		for _, f := range defs.live {
+			liveFields[f.Name] = f
+		}
+		desiredFields := make([]namedDefinition, len(defs.desired))
+		for i, f := range defs.desired {
+			desiredFields[i] = namedDefinition{f.Name, f}
+		}
+		fieldDeletes, err := diffNamed(plan, defs.kind, liveFields, desiredFields, prune, defs.keep)
+		if err != nil {
+			return nil, err
+		}
+		deletes = append(deletes, fieldDeletes...)
+
+		deleted := make(map[string]bool)
+		for _, d := range fieldDeletes {
+			deleted[d.Name] = true
+		}
+		for _, f := range defs.live {
+			if !deleted[f.Name] {
+				usedTypes[f.Type] = true
+			}
+		}
+		for _, f := range defs.desired {
+			usedTypes[f.Type] = true
+		}
+	}
+	unusedTypeDeletes := typeDeletes[:0]
+	for _, d := range typeDeletes {
+		if !usedTypes[d.Name] {
+			unusedTypeDeletes = append(unusedTypeDeletes, d)
+		}
+	}
+
+	// new copy fields may refer to new fields, and fields must be deleted before their types
+	plan.Changes = append(plan.Changes, addCopies...)
+	plan.Changes = append(plan.Changes, deletes...)
+	plan.Changes = append(plan.Changes, unusedTypeDeletes...)
+	return plan, nil
+}
+
+type namedDefinition struct {
+	name  string
+	value interface{}
+}
+
+// diffNamed adds the add and replace commands for one kind of named definition to the plan, and returns the
+// delete commands, which have to be applied last. keep names a definition that is never deleted.
+func diffNamed(plan *SchemaPlan, kind string, live map[string]interface{}, desired []namedDefinition, prune bool, keep string) ([]SchemaChange, error) {
+	seen := make(map[string]bool)
+	for _, d := range desired {
+		seen[d.name] = true
+		cur, exists := live[d.name]
+		if !exists {
+			plan.Changes = append(plan.Changes, SchemaChange{Command: "add-" + kind, Name: d.name, Desired: d.value})
+			continue
+		}
+		same, err := jsonSubset(d.value, cur)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			plan.Changes = append(plan.Changes, SchemaChange{Command: "replace-" + kind, Name: d.name, Current: cur, Desired: d.value})
+		}
+	}
+
+	var deletes []SchemaChange
+	if prune {
+		for name, cur := range live {
+			if seen[name] || name == keep || (len(name) > 1 && strings.HasPrefix(name, "_") && strings.HasSuffix(name, "_")) {
+				continue
+			}
+			deletes = append(deletes, SchemaChange{Command: "delete-" + kind, Name: name, Current: cur})
+		}
+		// map order is random, keep plans stable
+		sort.Slice(deletes, func(i, j int) bool { return deletes[i].Name < deletes[j].Name })
+	}
+	return deletes, nil
+}
+
+// jsonSubset reports whether every member set in the JSON form of desired has the same value in current
+func jsonSubset(desired interface{}, current interface{}) (bool, error) {
+	var d, c map[string]interface{}
+	for _, pair := range []struct {
+		v   interface{}
+		dst *map[string]interface{}
+	}{{desired, &d}, {current, &c}} {
+		b, err := json.Marshal(pair.v)
+		if err != nil {
+			return false, err
+		}
+		if err := json.Unmarshal(b, pair.dst); err != nil {
+			return false, err
+		}
+	}
+	for k, v := range d {
+		if !reflect.DeepEqual(v, c[k]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
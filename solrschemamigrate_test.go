@@ -0,0 +1,248 @@
+package solrg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// liveSchemaServer serves a fixed live schema and records schema updates
+func liveSchemaServer(posted *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/test/schema/fieldtypes":
+			fmt.Fprint(w, `{"fieldTypes":[
+				{"name":"string","class":"solr.StrField","sortMissingLast":true,"docValues":true},
+				{"name":"text_ws","class":"solr.TextField","positionIncrementGap":"100","analyzer":{"tokenizer":{"class":"solr.StandardTokenizerFactory"}}},
+				{"name":"plong","class":"solr.LongPointField","docValues":true},
+				{"name":"old_type","class":"solr.StrField"}]}`)
+		case "/test/schema/fields":
+			fmt.Fprint(w, `{"fields":[
+				{"name":"_version_","type":"plong","indexed":false,"stored":false},
+				{"name":"id","type":"string","multiValued":false,"indexed":true,"required":true,"stored":true},
+				{"name":"title","type":"string","stored":true},
+				{"name":"obsolete","type":"old_type"}]}`)
+		case "/test/schema/dynamicfields":
+			fmt.Fprint(w, `{"dynamicFields":[{"name":"*_s","type":"string"},{"name":"*_i","type":"pint"}]}`)
+		case "/test/schema/uniquekey":
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":0},"uniqueKey":"id"}`)
+		case "/test/schema/copyfields":
+			fmt.Fprint(w, `{"copyFields":[{"source":"title","dest":"_text_"},{"source":"tags","dest":"_text_"},{"source":"obsolete","dest":"_text_"}]}`)
+		case "/test/schema":
+			b, _ := ioutil.ReadAll(r.Body)
+			*posted = append(*posted, string(b))
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":5}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestMigrateSchema(t *testing.T) {
+	var posted []string
+	srv := liveSchemaServer(&posted)
+	defer srv.Close()
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	desired, err := ReadSchemaDefinitionFile("testdata/schema_definition.json")
+	must(err)
+
+	plan, err := sc.MigrateSchema("test", desired, SchemaMigrationOptions{DryRun: true})
+	must(err)
+	if len(posted) != 0 {
+		t.Errorf("A dry run should not change the schema")
+	}
+	var commands []string
+	for _, c := range plan.Changes {
+		commands = append(commands, c.Command+" "+c.Name)
+	}
+	expected := []string{
+		"delete-copy-field tags -> _text_",
+		"replace-field-type text_ws",
+		"replace-field title",
+		"add-field tags",
+		"add-copy-field tags -> _text_",
+	}
+	if strings.Join(commands, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected plan:\n%s", plan)
+	}
+
+	plan, err = sc.MigrateSchema("test", desired, SchemaMigrationOptions{Prune: true})
+	must(err)
+	commands = commands[:0]
+	for _, c := range plan.Changes {
+		commands = append(commands, c.Command+" "+c.Name)
+	}
+	expected = []string{
+		"delete-copy-field tags -> _text_",
+		"delete-copy-field obsolete -> _text_",
+		"replace-field-type text_ws",
+		"replace-field title",
+		"add-field tags",
+		"add-copy-field tags -> _text_",
+		"delete-field obsolete",
+		"delete-dynamic-field *_i",
+		"delete-field-type old_type",
+	}
+	if strings.Join(commands, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected pruning plan:\n%s", plan)
+	}
+	if len(posted) != 1 {
+		t.Fatalf("Expected a single schema request, got %d", len(posted))
+	}
+	for _, cmd := range []string{
+		`"delete-copy-field":{"source":"obsolete","dest":"_text_"}`,
+		`"add-copy-field":{"source":"tags","dest":"_text_","maxChars":100}`,
+		`"delete-field":{"name":"obsolete"}`,
+		`"add-field":{"name":"tags","type":"string","multiValued":true}`,
+	} {
+		if !strings.Contains(posted[0], cmd) {
+			t.Errorf("Expected %s in %s", cmd, posted[0])
+		}
+	}
+	if strings.Contains(posted[0], "_version_") {
+		t.Errorf("Internal fields should never be deleted: %s", posted[0])
+	}
+
+	// applying the definition to a matching schema is a no-op
+	live, err := sc.Schema("test")
+	must(err)
+	plan, err = DiffSchema(live, live, true)
+	must(err)
+	if !plan.Empty() || plan.String() != "No schema changes" {
+		t.Errorf("Expected an empty plan, got %s", plan)
+	}
+
+	if _, err := ReadSchemaDefinition(strings.NewReader(`{"feilds":[]}`)); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+}
+
+func TestMigrateSchemaKeepsUniqueKey(t *testing.T) {
+	var posted []string
+	srv := liveSchemaServer(&posted)
+	defer srv.Close()
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	desired := &SchemaDefinition{Fields: []SchemaField{{Name: "title", Type: "string"}}}
+	plan, err := sc.MigrateSchema("test", desired, SchemaMigrationOptions{Prune: true, DryRun: true})
+	must(err)
+	for _, c := range plan.Changes {
+		if c.Name == "id" || c.Name == "string" {
+			t.Errorf("The unique key and its type should never be deleted: %s", plan)
+		}
+	}
+	if !strings.Contains(plan.String(), "delete-field obsolete") {
+		t.Errorf("Expected the other fields to be pruned: %s", plan)
+	}
+}
+
+// schemaStore is a live schema that applies field commands. Like Solr, fields only list the properties they
+// inherit from their type with showDefaults=true.
+type schemaStore struct {
+	typeDefaults map[string]map[string]interface{}
+	fields       map[string]map[string]interface{}
+}
+
+func (st *schemaStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/test/schema/fields":
+		var fields []map[string]interface{}
+		for _, name := range sortedKeysOf(st.fields) {
+			f := make(map[string]interface{})
+			if r.URL.Query().Get("showDefaults") == "true" {
+				for k, v := range st.typeDefaults[st.fields[name]["type"].(string)] {
+					f[k] = v
+				}
+			}
+			for k, v := range st.fields[name] {
+				f[k] = v
+			}
+			fields = append(fields, f)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"fields": fields})
+	case "/test/schema/uniquekey":
+		fmt.Fprint(w, `{"uniqueKey":"id"}`)
+	case "/test/schema":
+		// commands are repeated keys of one object
+		dec := json.NewDecoder(r.Body)
+		dec.Token()
+		for dec.More() {
+			cmd, _ := dec.Token()
+			var f map[string]interface{}
+			must(dec.Decode(&f))
+			switch cmd {
+			case "add-field", "replace-field":
+				st.fields[f["name"].(string)] = f
+			case "delete-field":
+				delete(st.fields, f["name"].(string))
+			}
+		}
+		fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":5}}`)
+	default:
+		fmt.Fprint(w, `{}`)
+	}
+}
+
+func sortedKeysOf(m map[string]map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestMigrateSchemaConverges(t *testing.T) {
+	store := &schemaStore{
+		typeDefaults: map[string]map[string]interface{}{
+			"string": {"indexed": true, "stored": true, "docValues": true, "multiValued": false},
+		},
+		fields: map[string]map[string]interface{}{
+			"id": {"name": "id", "type": "string", "required": true},
+		},
+	}
+	srv := httptest.NewServer(store)
+	defer srv.Close()
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	// stored and indexed are the type defaults, Solr doesn't list them on the fields
+	desired, err := ReadSchemaDefinition(strings.NewReader(`{"fields":[
+		{"name":"id","type":"string","required":true,"stored":true},
+		{"name":"title","type":"string","indexed":true,"stored":true}]}`))
+	must(err)
+	plan, err := sc.MigrateSchema("test", desired, SchemaMigrationOptions{Prune: true})
+	must(err)
+	if plan.String() != "+ add-field title {\"name\":\"title\",\"type\":\"string\",\"indexed\":true,\"stored\":true}\n" {
+		t.Errorf("Unexpected plan:\n%s", plan)
+	}
+
+	plan, err = sc.MigrateSchema("test", desired, SchemaMigrationOptions{Prune: true})
+	must(err)
+	if !plan.Empty() {
+		t.Errorf("Expected an empty plan once applied, got\n%s", plan)
+	}
+}
+
+func TestReadSchemaDefinitionYAML(t *testing.T) {
+	fromJSON, err := ReadSchemaDefinitionFile("testdata/schema_definition.json")
+	must(err)
+	fromYAML, err := ReadSchemaDefinitionFile("testdata/schema_definition.yaml")
+	must(err)
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		t.Errorf("YAML definition differs from JSON:\n%+v\n%+v", fromYAML, fromJSON)
+	}
+
+	if _, err := ReadSchemaDefinitionYAML(strings.NewReader("feilds: []\n")); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+}
@@ -0,0 +1,215 @@
+package solrg
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SchemaFromStruct derives field and copy field definitions from the solr tags of a struct, so the same struct
+// can describe both the schema and the documents indexed with PostStructs. Besides the name and the omitempty
+// and multi options, a tag can hold these schema options:
+//
+//	type=text_en         the field type, derived from the Go type if not set
+//	indexed, stored, docValues, multiValued, required, uninvertible
+//	                     set a property to true, or to false with e.g. stored=false
+//	default=value        the default value of the field
+//	copyTo=dest          copies the field to dest, separate several destinations with |
+//	noschema             leaves the field out, e.g. because a dynamic field covers it
+//
+// Without type=, strings map to string, bools to boolean, int8-int32 and uint8-uint16 to pint, int, int64 and
+// the wider unsigned integers to plong (int is 64 bits on most platforms), float32 to pfloat, float64 to
+// pdouble, time.Time to pdate and []byte to binary. Slices are multiValued. Struct fields hold child documents, which share the schema of their parent,
+// so the fields of child structs are included too. v must be a struct or a pointer to one.
+func SchemaFromStruct(v interface{}) (*SchemaDefinition, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("SchemaFromStruct requires a struct, got %T", v)
+	}
+	def := &SchemaDefinition{}
+	if err := addStructSchema(def, t, map[reflect.Type]bool{}); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// addStructSchema adds the fields of a struct type and its child structs to def
+func addStructSchema(def *SchemaDefinition, t reflect.Type, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	for _, sf := range cachedStructFields(t) {
+		ft := t.FieldByIndex(sf.index).Type
+		if child := childStructType(ft); child != nil {
+			if err := addStructSchema(def, child, seen); err != nil {
+				return err
+			}
+			continue
+		}
+
+		field := SchemaField{Name: sf.name}
+		skip := false
+		for _, o := range sf.options {
+			key, value := o, ""
+			if i := strings.Index(o, "="); i >= 0 {
+				key, value = o[:i], o[i+1:]
+			}
+			var prop **bool
+			switch key {
+			case "omitempty", "multi":
+				continue
+			case "noschema":
+				skip = true
+				continue
+			case "type":
+				field.Type = value
+				continue
+			case "default":
+				field.Default = value
+				continue
+			case "copyTo":
+				for _, dest := range strings.Split(value, "|") {
+					def.CopyFields = append(def.CopyFields, CopyField{Source: sf.name, Dest: dest})
+				}
+				continue
+			case "indexed":
+				prop = &field.Indexed
+			case "stored":
+				prop = &field.Stored
+			case "docValues":
+				prop = &field.DocValues
+			case "multiValued":
+				prop = &field.MultiValued
+			case "required":
+				prop = &field.Required
+			case "uninvertible":
+				prop = &field.Uninvertible
+			default:
+				return fmt.Errorf("Unknown solr tag option %q on field %s", o, sf.name)
+			}
+			b := true
+			if value != "" {
+				var err error
+				if b, err = strconv.ParseBool(value); err != nil {
+					return fmt.Errorf("Invalid solr tag option %q on field %s: %s", o, sf.name, err)
+				}
+			}
+			*prop = &b
+		}
+		if skip {
+			continue
+		}
+
+		typeName, multi, ok := solrTypeOf(ft)
+		if field.Type == "" {
+			if !ok {
+				return fmt.Errorf("Unable to derive a Solr field type for field %s of type %s, set one with type= in its solr tag", sf.name, ft)
+			}
+			field.Type = typeName
+		}
+		if field.MultiValued == nil && (multi || sf.multi) {
+			field.MultiValued = Bool(true)
+		}
+		if err := addSchemaField(def, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addSchemaField adds a field to def. A field defined by both a parent and a child struct is merged, as long as
+// the two don't set a property to different values.
+func addSchemaField(def *SchemaDefinition, field SchemaField) error {
+	for i, f := range def.Fields {
+		if f.Name != field.Name {
+			continue
+		}
+		existing, added := reflect.ValueOf(&def.Fields[i]).Elem(), reflect.ValueOf(field)
+		for j := 0; j < existing.NumField(); j++ {
+			ev, av := existing.Field(j), added.Field(j)
+			if av.IsZero() {
+				continue
+			}
+			if ev.IsZero() {
+				ev.Set(av)
+				continue
+			}
+			if ev.Kind() == reflect.Ptr {
+				ev, av = ev.Elem(), av.Elem()
+			}
+			if ev.Interface() != av.Interface() {
+				return fmt.Errorf("Field %s is defined differently in a parent and a child struct", field.Name)
+			}
+		}
+		return nil
+	}
+	def.Fields = append(def.Fields, field)
+	return nil
+}
+
+// childStructType returns the struct type of a field holding child documents, or nil
+func childStructType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && t != timeType && !t.Implements(jsonMarshalerType) && !reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return t
+	}
+	return nil
+}
+
+// solrTypeOf returns the default field type for a Go type and whether the field is multivalued
+func solrTypeOf(t reflect.Type) (string, bool, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "pdate", false, true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string", false, true
+	case reflect.Bool:
+		return "boolean", false, true
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return "pint", false, true
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "plong", false, true
+	case reflect.Float32:
+		return "pfloat", false, true
+	case reflect.Float64:
+		return "pdouble", false, true
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "binary", false, true
+		}
+		name, _, ok := solrTypeOf(t.Elem())
+		return name, true, ok
+	}
+	return "", false, false
+}
+
+// CreateRequest returns a SchemaRequest adding everything in the definition to a schema, in an order Solr
+// accepts: field types, fields, dynamic fields and then copy fields. Use MigrateSchema to update an existing schema.
+func (def *SchemaDefinition) CreateRequest() *SchemaRequest {
+	req := NewSchemaRequest()
+	for _, ft := range def.FieldTypes {
+		req.AddFieldType(ft)
+	}
+	for _, f := range def.Fields {
+		req.AddField(f)
+	}
+	for _, f := range def.DynamicFields {
+		req.AddDynamicField(f)
+	}
+	for _, cf := range def.CopyFields {
+		req.AddCopyField(cf)
+	}
+	return req
+}
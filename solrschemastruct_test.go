@@ -0,0 +1,77 @@
+package solrg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type schemaReview struct {
+	ID    string `solr:"id"`
+	Stars int32  `solr:"stars_i,stored=false,docValues"`
+}
+
+type schemaBook struct {
+	ID        string         `solr:"id,required"`
+	Title     string         `solr:"title,type=text_en,copyTo=_text_|title_sort"`
+	Authors   []string       `solr:"authors,omitempty"`
+	Pages     int            `solr:"pages"`
+	Copies    uint           `solr:"copies"`
+	ISBN      int64          `json:"isbn"`
+	Price     float64        `solr:"price,indexed=false"`
+	Published time.Time      `solr:"published"`
+	Cover     []byte         `solr:"cover,omitempty"`
+	Genre     string         `solr:"genre_s,noschema"`
+	Tags      string         `solr:"tags,multi"`
+	Reviews   []schemaReview `solr:"reviews"`
+	Internal  string         `solr:"-"`
+}
+
+func TestSchemaFromStruct(t *testing.T) {
+	def, err := SchemaFromStruct(&schemaBook{})
+	must(err)
+
+	var buf bytes.Buffer
+	must(def.CreateRequest().WriteJSON(&buf))
+	expected := `{"add-field":{"name":"id","type":"string","required":true}
+,"add-field":{"name":"title","type":"text_en"}
+,"add-field":{"name":"authors","type":"string","multiValued":true}
+,"add-field":{"name":"pages","type":"plong"}
+,"add-field":{"name":"copies","type":"plong"}
+,"add-field":{"name":"isbn","type":"plong"}
+,"add-field":{"name":"price","type":"pdouble","indexed":false}
+,"add-field":{"name":"published","type":"pdate"}
+,"add-field":{"name":"cover","type":"binary"}
+,"add-field":{"name":"tags","type":"string","multiValued":true}
+,"add-field":{"name":"stars_i","type":"pint","stored":false,"docValues":true}
+,"add-copy-field":{"source":"title","dest":"_text_"}
+,"add-copy-field":{"source":"title","dest":"title_sort"}
+}`
+	if buf.String() != expected {
+		t.Errorf("Unexpected schema:\n%s", buf.String())
+	}
+
+	type badOption struct {
+		Name string `solr:"name,stored=maybe"`
+	}
+	if _, err := SchemaFromStruct(badOption{}); err == nil || !strings.Contains(err.Error(), "stored=maybe") {
+		t.Errorf("Expected an invalid option error, got %v", err)
+	}
+	type noType struct {
+		Attrs map[string]string `solr:"attrs"`
+	}
+	if _, err := SchemaFromStruct(noType{}); err == nil {
+		t.Error("Expected an error for a field without a derivable type")
+	}
+	type conflict struct {
+		ID       string         `solr:"id,type=text_general"`
+		Children []schemaReview `solr:"children"`
+	}
+	if _, err := SchemaFromStruct(conflict{}); err == nil {
+		t.Error("Expected an error for a field defined differently in a child struct")
+	}
+	if _, err := SchemaFromStruct("not a struct"); err == nil {
+		t.Error("Expected an error for a non struct")
+	}
+}
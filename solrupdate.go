@@ -0,0 +1,343 @@
+package solrg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UpdateOption sets a request parameter on an indexing or delete call
+type UpdateOption func(params url.Values)
+
+// CommitWithin asks Solr to commit the changes within d
+func CommitWithin(d time.Duration) UpdateOption {
+	return func(params url.Values) {
+		params.Set("commitWithin", strconv.FormatInt(int64(d/time.Millisecond), 10))
+	}
+}
+
+// ReturnVersions asks Solr to echo back the _version_ of every added or deleted document
+func ReturnVersions() UpdateOption {
+	return func(params url.Values) {
+		params.Set("versions", "true")
+	}
+}
+
+// MaxErrors enables Solr's TolerantUpdateProcessor behaviour: up to maxErrors documents may fail without
+// failing the whole request, and the failures are reported in UpdateResponse.Errors. Methods that only
+// return an error, like PostDocs, return them as a *TolerantUpdateError. -1 tolerates any number of errors.
+// The collection's update chain must include a TolerantUpdateProcessorFactory (see UpdateChain).
+func MaxErrors(maxErrors int) UpdateOption {
+	return func(params url.Values) {
+		params.Set("maxErrors", strconv.Itoa(maxErrors))
+	}
+}
+
+// UpdateChain selects the update request processor chain used for the request
+func UpdateChain(name string) UpdateOption {
+	return func(params url.Values) {
+		params.Set("update.chain", name)
+	}
+}
+
+// updateParams applies options to a new set of request parameters
+func updateParams(opts []UpdateOption) url.Values {
+	params := url.Values{}
+	for _, opt := range opts {
+		opt(params)
+	}
+	return params
+}
+
+// DeleteCommand deletes a single document by id. Version and Route are optional; Version uses the same
+// optimistic concurrency rules as SolrDocument.SetVersion and Route is the _route_ used by implicit routers.
+type DeleteCommand struct {
+	ID      string `json:"id"`
+	Version int64  `json:"_version_,omitempty"`
+	Route   string `json:"_route_,omitempty"`
+}
+
+type updateCommand struct {
+	name  string
+	value interface{}
+}
+
+// UpdateRequest combines adds, deletes, commits and optimizes into a single JSON update command
+// body that Solr executes in order in one HTTP call.
+// See https://lucene.apache.org/solr/guide/7_4/uploading-data-with-index-handlers.html#sending-json-update-commands
+type UpdateRequest struct {
+	commands []updateCommand
+}
+
+// NewUpdateRequest returns a new, empty UpdateRequest
+func NewUpdateRequest() *UpdateRequest {
+	return &UpdateRequest{}
+}
+
+// AddDocs adds documents to the request
+func (ur *UpdateRequest) AddDocs(docs ...SolrDocument) error {
+	for _, doc := range docs {
+		if err := validateDocIDs(doc); err != nil {
+			return err
+		}
+		ur.commands = append(ur.commands, updateCommand{"add", doc})
+	}
+	return nil
+}
+
+// DeleteByID deletes documents by id
+func (ur *UpdateRequest) DeleteByID(ids ...string) {
+	if len(ids) == 0 {
+		return
+	}
+	ur.commands = append(ur.commands, updateCommand{"delete", ids})
+}
+
+// Delete deletes documents by id, with an optional expected _version_ and _route_ per document
+func (ur *UpdateRequest) Delete(cmds ...DeleteCommand) {
+	for _, cmd := range cmds {
+		ur.commands = append(ur.commands, updateCommand{"delete", cmd})
+	}
+}
+
+// DeleteByQuery deletes all documents matching a query
+func (ur *UpdateRequest) DeleteByQuery(query string) {
+	ur.commands = append(ur.commands, updateCommand{"delete", map[string]string{"query": query}})
+}
+
+// Commit commits all of the preceding commands
+func (ur *UpdateRequest) Commit() {
+	ur.commands = append(ur.commands, updateCommand{"commit", struct{}{}})
+}
+
+// CommitWithOptions commits all of the preceding commands. opts.Timeout is ignored.
+func (ur *UpdateRequest) CommitWithOptions(opts CommitOptions) {
+	ur.commands = append(ur.commands, updateCommand{"commit", opts})
+}
+
+// Optimize optimizes the index after the preceding commands
+func (ur *UpdateRequest) Optimize() {
+	ur.commands = append(ur.commands, updateCommand{"optimize", struct{}{}})
+}
+
+// OptimizeSegments optimizes the index down to at most maxSegments segments after the preceding commands
+func (ur *UpdateRequest) OptimizeSegments(maxSegments int) {
+	ur.commands = append(ur.commands, updateCommand{"optimize", map[string]int{"maxSegments": maxSegments}})
+}
+
+// NumCommands returns the number of commands in the request
+func (ur *UpdateRequest) NumCommands() int {
+	return len(ur.commands)
+}
+
+// WriteJSON streams the request to w as a JSON update command object. Solr allows repeated
+// keys in update command objects, which is how multiple commands are combined.
+func (ur *UpdateRequest) WriteJSON(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	bw.WriteByte('{')
+	for i, cmd := range ur.commands {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteString(`"` + cmd.name + `":`)
+		value := cmd.value
+		if doc, ok := value.(SolrDocument); ok {
+			m, err := doc.solrMap()
+			if err != nil {
+				return fmt.Errorf("Error creating json for document %s: %s", doc.ID(), err)
+			}
+			value = map[string]interface{}{"doc": m}
+		}
+		if err := enc.Encode(value); err != nil {
+			return err
+		}
+	}
+	bw.WriteByte('}')
+	return bw.Flush()
+}
+
+// Update executes all of the commands in an UpdateRequest in a single call
+func (sc *SolrClient) Update(collection string, req *UpdateRequest, opts ...UpdateOption) (*UpdateResponse, error) {
+	var client = &http.Client{
+		Timeout: time.Second * 10,
+	}
+	return sc.postStream(client, collection, updateParams(opts), req.WriteJSON)
+}
+
+// DeleteByID deletes documents by id
+func (sc *SolrClient) DeleteByID(collection string, ids ...string) error {
+	return sc.DeleteByIDWithOptions(collection, ids)
+}
+
+// DeleteByIDWithOptions deletes documents by id, e.g. with CommitWithin
+func (sc *SolrClient) DeleteByIDWithOptions(collection string, ids []string, opts ...UpdateOption) error {
+	req := NewUpdateRequest()
+	req.DeleteByID(ids...)
+	return toleratedError(sc.Update(collection, req, opts...))
+}
+
+// UpdateResponse holds Solr's response to an update request
+type UpdateResponse struct {
+	ResponseHeader struct {
+		Status int `json:"status"`
+		QTime  int `json:"QTime"`
+		// RF is the achieved replication factor, returned by SolrCloud
+		RF int `json:"rf"`
+		// MaxErrors is the number of tolerated errors, returned when using MaxErrors
+		MaxErrors int `json:"maxErrors"`
+	} `json:"responseHeader"`
+	// Errors lists the documents that failed when using MaxErrors. The rest of the request succeeded.
+	Errors []DocumentError `json:"-"`
+	// Adds maps the ids of added documents to their new _version_ (requires ReturnVersions)
+	Adds map[string]int64 `json:"-"`
+	// Deletes maps the ids of deleted documents to the _version_ of the delete (requires ReturnVersions)
+	Deletes map[string]int64 `json:"-"`
+	// DeletesByQuery maps delete queries to the _version_ of the delete (requires ReturnVersions)
+	DeletesByQuery map[string]int64 `json:"-"`
+	// Node is the address of the Solr node that served the request
+	Node string `json:"-"`
+	// Elapsed is the round trip time of the request
+	Elapsed time.Duration `json:"-"`
+}
+
+// parseUpdateResponse decodes the body of a successful update request
+func parseUpdateResponse(body []byte) (*UpdateResponse, error) {
+	var resp UpdateResponse
+	if len(body) == 0 {
+		return &resp, nil
+	}
+	var raw struct {
+		// the TolerantUpdateProcessor reports errors in the response header
+		ResponseHeader struct {
+			Errors []DocumentError `json:"errors"`
+		} `json:"responseHeader"`
+		Adds          json.RawMessage `json:"adds"`
+		Deletes       json.RawMessage `json:"deletes"`
+		DeleteByQuery json.RawMessage `json:"deleteByQuery"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("Error parsing update response: %s", err)
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("Error parsing update response: %s", err)
+	}
+	resp.Errors = raw.ResponseHeader.Errors
+	var err error
+	if resp.Adds, err = parseVersions(raw.Adds); err != nil {
+		return nil, err
+	}
+	if resp.Deletes, err = parseVersions(raw.Deletes); err != nil {
+		return nil, err
+	}
+	if resp.DeletesByQuery, err = parseVersions(raw.DeleteByQuery); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// parseVersions reads an id -> _version_ named list, either in Solr's default flat
+// ["id1",version1,"id2",version2] format or as a json.nl=map object
+func parseVersions(raw json.RawMessage) (map[string]int64, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]int64)
+	switch nl := v.(type) {
+	case []interface{}:
+		for i := 0; i+1 < len(nl); i += 2 {
+			id, _ := nl[i].(string)
+			version, ok := toInt64(nl[i+1])
+			if !ok {
+				return nil, fmt.Errorf("Unable to read version of %s from update response", id)
+			}
+			versions[id] = version
+		}
+	case map[string]interface{}:
+		for id, val := range nl {
+			version, ok := toInt64(val)
+			if !ok {
+				return nil, fmt.Errorf("Unable to read version of %s from update response", id)
+			}
+			versions[id] = version
+		}
+	}
+	return versions, nil
+}
+
+// DocumentError describes a single failed command reported by the TolerantUpdateProcessor
+type DocumentError struct {
+	// ID is the id of the document, or the query of a failed delete by query
+	ID string `json:"id"`
+	// Type is the type of command that failed: ADD, DELID or DELQ
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// TolerantUpdateError is returned when more documents failed than allowed by MaxErrors. Methods that only
+// return an error, like PostDocs, also return it for documents that failed within MaxErrors, with a nil Err.
+type TolerantUpdateError struct {
+	Errors []DocumentError
+	Msg    string
+	Err    *SolrError
+}
+
+func (e *TolerantUpdateError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%d documents failed, the rest of the update succeeded: %s", len(e.Errors), e.Msg)
+	}
+	return fmt.Sprintf("%d documents failed, exceeding maxErrors: %s", len(e.Errors), e.Msg)
+}
+
+// Unwrap returns the underlying SolrError, if the whole request failed
+func (e *TolerantUpdateError) Unwrap() error {
+	if e.Err == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// toleratedError returns the documents that failed within MaxErrors as a *TolerantUpdateError, for methods
+// that don't return the UpdateResponse
+func toleratedError(resp *UpdateResponse, err error) error {
+	if err != nil || len(resp.Errors) == 0 {
+		return err
+	}
+	return &TolerantUpdateError{Errors: resp.Errors, Msg: resp.Errors[0].Message}
+}
+
+// toleratedErrorsFromMetadata reads the per document errors Solr includes in the error metadata when a
+// request exceeds maxErrors. Metadata is a flat list of key/value pairs, with tolerated errors keyed as
+// "org.apache.solr.common.ToleratedUpdateError--<TYPE>:<id>".
+func toleratedErrorsFromMetadata(metadata []string) []DocumentError {
+	const prefix = "org.apache.solr.common.ToleratedUpdateError--"
+	var errs []DocumentError
+	for i := 0; i+1 < len(metadata); i += 2 {
+		key := metadata[i]
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(key, prefix), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		errs = append(errs, DocumentError{Type: parts[0], ID: parts[1], Message: metadata[i+1]})
+	}
+	return errs
+}
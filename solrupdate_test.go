@@ -0,0 +1,169 @@
+package solrg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateRequestJSON(t *testing.T) {
+	doc := NewSolrDocument("1")
+	doc.SetInt("n_i", 1)
+
+	req := NewUpdateRequest()
+	must(req.AddDocs(doc))
+	req.DeleteByID("2", "3")
+	req.Delete(DeleteCommand{ID: "4", Version: 1612345678901234567, Route: "shard1"})
+	req.DeleteByQuery(`type_s:"old"`)
+	req.Commit()
+	req.Optimize()
+
+	var buf bytes.Buffer
+	must(req.WriteJSON(&buf))
+
+	expected := `{"add":{"doc":{"id":"1","n_i":1}}
+,"delete":["2","3"]
+,"delete":{"id":"4","_version_":1612345678901234567,"_route_":"shard1"}
+,"delete":{"query":"type_s:\"old\""}
+,"commit":{}
+,"optimize":{}
+}`
+	if buf.String() != expected {
+		t.Errorf("Unexpected update request json.\nexpected: %s\ngot:      %s", expected, buf.String())
+	}
+
+	if err := req.AddDocs(SolrDocument{fields: map[string]interface{}{}}); err == nil {
+		t.Error("Expected an error adding a doc without an id")
+	}
+}
+
+func TestCommitOptionsAndCommitWithin(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+" "+string(body))
+		fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	must(sc.CommitWithOptions("test", CommitOptions{SoftCommit: true, WaitSearcher: Bool(false)}))
+	must(sc.Optimize("test", 2))
+	must(sc.OptimizeWithOptions("test", OptimizeOptions{Timeout: time.Minute}))
+	must(sc.Rollback("test"))
+	docs := fakeDocs()
+	must(sc.PostDocs(&docs, "test", CommitWithin(5*time.Second)))
+	must(sc.DeleteByQuery("test", "*:*", CommitWithin(time.Second)))
+
+	req := NewUpdateRequest()
+	req.CommitWithOptions(CommitOptions{ExpungeDeletes: true})
+	_, err = sc.Update("test", req)
+	must(err)
+
+	expected := []string{
+		"GET /test/update?commit=true&softCommit=true&waitSearcher=false ",
+		"GET /test/update?maxSegments=2&optimize=true ",
+		"GET /test/update?optimize=true ",
+		`POST /test/update? {"rollback":{}}`,
+		"", // docs are in random order, only the params are checked
+		`POST /test/update?commitWithin=1000 {"delete":{"query":"*:*"}}`,
+		"POST /test/update? {\"commit\":{\"expungeDeletes\":true}\n}",
+	}
+	if len(requests) != len(expected) {
+		t.Fatalf("Expected %d requests, got %d: %v", len(expected), len(requests), requests)
+	}
+	for i, e := range expected {
+		if e == "" {
+			if !strings.HasPrefix(requests[i], "POST /test/update?commitWithin=5000 [") {
+				t.Errorf("Unexpected request %s", requests[i])
+			}
+			continue
+		}
+		if requests[i] != e {
+			t.Errorf("Unexpected request.\nexpected: %s\ngot:      %s", e, requests[i])
+		}
+	}
+}
+
+func TestUpdateResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("versions") != "true" {
+			t.Errorf("Expected versions=true, got %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"responseHeader":{"rf":2,"status":0,"QTime":7},"adds":["1",1612345678901234567,"2",1612345678901234568],"deletes":["3",-1612345678901234569]}`)
+	}))
+	defer srv.Close()
+
+	node := strings.TrimPrefix(srv.URL, "http://")
+	sc, err := NewDirectSolrClient(node)
+	must(err)
+
+	req := NewUpdateRequest()
+	must(req.AddDocs(NewSolrDocument("1"), NewSolrDocument("2")))
+	req.DeleteByID("3")
+	resp, err := sc.Update("test", req, ReturnVersions())
+	must(err)
+
+	if resp.ResponseHeader.QTime != 7 || resp.ResponseHeader.RF != 2 || resp.Node != node {
+		t.Errorf("Unexpected response header or node: %+v", resp)
+	}
+	if resp.Adds["1"] != 1612345678901234567 || resp.Adds["2"] != 1612345678901234568 {
+		t.Errorf("Unexpected add versions %v", resp.Adds)
+	}
+	if resp.Deletes["3"] != -1612345678901234569 {
+		t.Errorf("Unexpected delete versions %v", resp.Deletes)
+	}
+}
+
+func TestTolerantUpdates(t *testing.T) {
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("maxErrors") != "1" || r.URL.Query().Get("update.chain") != "tolerant-chain" {
+			t.Errorf("Unexpected params %s", r.URL.RawQuery)
+		}
+		if fail {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"responseHeader":{"status":400,"QTime":3},"error":{"metadata":["org.apache.solr.common.ToleratedUpdateError--ADD:1","ERROR: [doc=1] bad value","org.apache.solr.common.ToleratedUpdateError--ADD:2","ERROR: [doc=2] bad value","error-class","org.apache.solr.common.SolrException"],"msg":"ToleratedUpdateErrors (2 exceeds maxErrors 1)","code":400}}`)
+			return
+		}
+		fmt.Fprint(w, `{"responseHeader":{"errors":[{"type":"ADD","id":"1","message":"ERROR: [doc=1] bad value"}],"maxErrors":1,"status":0,"QTime":3}}`)
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+	docs := fakeDocs()
+
+	resp, err := sc.PostDocsWithResponse(&docs, "test", MaxErrors(1), UpdateChain("tolerant-chain"))
+	must(err)
+	expected := []DocumentError{{ID: "1", Type: "ADD", Message: "ERROR: [doc=1] bad value"}}
+	if !reflect.DeepEqual(resp.Errors, expected) {
+		t.Errorf("Unexpected document errors %v", resp.Errors)
+	}
+
+	err = sc.PostDocs(&docs, "test", MaxErrors(1), UpdateChain("tolerant-chain"))
+	var tolerated *TolerantUpdateError
+	var se *SolrError
+	if !errors.As(err, &tolerated) || !reflect.DeepEqual(tolerated.Errors, expected) || errors.As(err, &se) {
+		t.Errorf("Expected the tolerated errors without a SolrError, got %v", err)
+	}
+
+	fail = true
+	_, err = sc.PostDocsWithResponse(&docs, "test", MaxErrors(1), UpdateChain("tolerant-chain"))
+	terr, ok := err.(*TolerantUpdateError)
+	if !ok {
+		t.Fatalf("Expected a *TolerantUpdateError, got %T: %v", err, err)
+	}
+	if len(terr.Errors) != 2 || terr.Errors[1].ID != "2" || terr.Errors[1].Type != "ADD" {
+		t.Errorf("Unexpected document errors %v", terr.Errors)
+	}
+}
@@ -0,0 +1,125 @@
+package solrg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Special _version_ values understood by Solr's optimistic concurrency support.
+// Any other positive value requires the indexed document to have exactly that version.
+const (
+	// VersionMustNotExist requires that the document does not exist
+	VersionMustNotExist int64 = -1
+	// VersionMustExist requires that the document exists, regardless of its version
+	VersionMustExist int64 = 1
+)
+
+var versionConflictRegex = regexp.MustCompile(`(?:version conflict for (\S+) expected=|Document not found for update\.\s+id=(\S+))`)
+
+// SetVersion sets the _version_ Solr must find on the indexed document for the update to succeed
+func (sd *SolrDocument) SetVersion(version int64) {
+	sd.fields["_version_"] = version
+}
+
+// ExpectExists makes the update fail unless the document already exists
+func (sd *SolrDocument) ExpectExists() {
+	sd.SetVersion(VersionMustExist)
+}
+
+// ExpectNotExists makes the update fail if the document already exists
+func (sd *SolrDocument) ExpectNotExists() {
+	sd.SetVersion(VersionMustNotExist)
+}
+
+// Version returns the _version_ of a document returned by Solr
+func (sd SolrSearchDocument) Version() (int64, error) {
+	v, ok := toInt64(sd["_version_"])
+	if !ok {
+		return 0, fmt.Errorf("Unable to read _version_ from document")
+	}
+	return v, nil
+}
+
+// VersionConflictError is returned when Solr rejects an update because a document's _version_ did not match
+type VersionConflictError struct {
+	IDs []string
+	Msg string
+	Err *SolrError
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("Version conflict for document(s) %s: %s", strings.Join(e.IDs, ", "), e.Msg)
+}
+
+// Unwrap returns the underlying SolrError
+func (e *VersionConflictError) Unwrap() error {
+	return e.Err
+}
+
+// newUpdateError builds the error returned for a failed update request
+func newUpdateError(resp *http.Response, body []byte) error {
+	se := newSolrError(resp, body)
+	if tolerated := toleratedErrorsFromMetadata(se.metadataPairs); len(tolerated) > 0 {
+		return &TolerantUpdateError{Errors: tolerated, Msg: se.Msg, Err: se}
+	}
+	if resp.StatusCode == http.StatusConflict {
+		e := &VersionConflictError{Msg: se.Msg, Err: se}
+		for _, m := range versionConflictRegex.FindAllStringSubmatch(se.Msg, -1) {
+			e.IDs = append(e.IDs, m[1]+m[2])
+		}
+		return e
+	}
+	return se
+}
+
+// ReadModifyWrite fetches the latest version of a document, passes it to modify and indexes the returned
+// document, requiring the _version_ in Solr to be unchanged. If another writer updated the document in the
+// meantime it is fetched again and modify re-applied, up to maxAttempts times (at least once). current is nil
+// if the document does not exist yet, in which case the update only succeeds if nobody else created it first.
+// modify must return a document built with NewSolrDocument, its id is set to id.
+func (sc *SolrClient) ReadModifyWrite(collection string, id string, maxAttempts int, modify func(current SolrSearchDocument) (SolrDocument, error)) error {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		current, err := sc.GetDoc(collection, id)
+		var notFound *DocumentNotFoundError
+		if errors.As(err, &notFound) {
+			current = nil
+		} else if err != nil {
+			return err
+		}
+		doc, err := modify(current)
+		if err != nil {
+			return err
+		}
+		if doc.fields == nil {
+			return fmt.Errorf("Error updating %s: modify returned an empty document, use NewSolrDocument", id)
+		}
+		doc.SetID(id)
+		if current == nil {
+			doc.ExpectNotExists()
+		} else {
+			version, err := current.Version()
+			if err != nil {
+				return err
+			}
+			doc.SetVersion(version)
+		}
+
+		docs := NewSolrDocumentCollection()
+		if err := docs.AddDoc(doc); err != nil {
+			return err
+		}
+		lastErr = sc.PostDocs(&docs, collection)
+		var conflict *VersionConflictError
+		if !errors.As(lastErr, &conflict) {
+			return lastErr
+		}
+	}
+	return lastErr
+}
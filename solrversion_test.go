@@ -0,0 +1,159 @@
+package solrg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVersionConflictError(t *testing.T) {
+	body := []byte(`{"responseHeader":{"status":409,"QTime":1},"error":{"metadata":["error-class","org.apache.solr.common.SolrException"],"msg":"version conflict for doc-1 expected=1 actual=1612345678901234567","code":409}}`)
+	err := newUpdateError(&http.Response{StatusCode: 409}, body)
+	verr, ok := err.(*VersionConflictError)
+	if !ok {
+		t.Fatalf("Expected a *VersionConflictError, got %T: %s", err, err)
+	}
+	if !reflect.DeepEqual(verr.IDs, []string{"doc-1"}) {
+		t.Errorf("Expected conflicting ids [doc-1], got %v", verr.IDs)
+	}
+
+	if !IsConflict(err) || verr.Err.ErrorClass != "org.apache.solr.common.SolrException" {
+		t.Errorf("Expected the underlying SolrError to be a conflict, got %+v", verr.Err)
+	}
+
+	err = newUpdateError(&http.Response{StatusCode: 400}, []byte(`{}`))
+	if _, ok := err.(*VersionConflictError); ok {
+		t.Error("A 400 response should not be a VersionConflictError")
+	}
+}
+
+func TestReadModifyWrite(t *testing.T) {
+	versions := []string{"1612345678901234567", "1612345678901234999"}
+	gets, posts := 0, 0
+	var sentVersions []interface{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/test/get":
+			fmt.Fprintf(w, `{"doc":{"id":"1","count_i":%d,"_version_":%s}}`, gets, versions[gets])
+			gets++
+		case "/test/update":
+			body, _ := ioutil.ReadAll(r.Body)
+			var docs []map[string]json.Number
+			must(json.Unmarshal(body, &docs))
+			sentVersions = append(sentVersions, string(docs[0]["_version_"]))
+			posts++
+			if posts == 1 {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, `{"error":{"msg":"version conflict for 1 expected=1612345678901234567 actual=1612345678901234999","code":409}}`)
+				return
+			}
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	err = sc.ReadModifyWrite("test", "1", 3, func(current SolrSearchDocument) (SolrDocument, error) {
+		n, err := current.Int64("count_i")
+		if err != nil {
+			return SolrDocument{}, err
+		}
+		doc := NewSolrDocument("1")
+		doc.SetInt("count_i", n+1)
+		return doc, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts != 2 {
+		t.Errorf("Expected 2 update attempts but there were %d", posts)
+	}
+	if !reflect.DeepEqual(sentVersions, []interface{}{versions[0], versions[1]}) {
+		t.Errorf("Expected the fetched versions to be sent without losing precision, got %v", sentVersions)
+	}
+}
+
+func TestReadModifyWriteNewDoc(t *testing.T) {
+	var sent map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/test/get":
+			fmt.Fprint(w, `{"doc":null}`)
+		case "/test/update":
+			body, _ := ioutil.ReadAll(r.Body)
+			var docs []map[string]interface{}
+			must(json.Unmarshal(body, &docs))
+			sent = docs[0]
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+
+	err = sc.ReadModifyWrite("test", "1", 1, func(current SolrSearchDocument) (SolrDocument, error) {
+		if current != nil {
+			t.Errorf("Expected no current document, got %v", current)
+		}
+		doc := NewSolrDocument("1")
+		doc.SetInt("count_i", 1)
+		return doc, nil
+	})
+	must(err)
+	if sent["_version_"] != float64(VersionMustNotExist) {
+		t.Errorf("Expected the new document to require it doesn't exist, got %v", sent)
+	}
+}
+
+func TestReadModifyWriteErrors(t *testing.T) {
+	posts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/test/get":
+			fmt.Fprint(w, `{"doc":null}`)
+		case "/test/update":
+			posts++
+			fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"msg":"Can not find: /missing/get","code":404}}`)
+		}
+	}))
+	defer srv.Close()
+
+	sc, err := NewDirectSolrClient(strings.TrimPrefix(srv.URL, "http://"))
+	must(err)
+	create := func(current SolrSearchDocument) (SolrDocument, error) {
+		return NewSolrDocument("1"), nil
+	}
+
+	if err := sc.ReadModifyWrite("missing", "1", 1, create); !IsNotFound(err) {
+		t.Errorf("Expected the missing collection's error, got %v", err)
+	}
+	if posts != 0 {
+		t.Errorf("A document was posted to a missing collection")
+	}
+
+	err = sc.ReadModifyWrite("test", "1", 1, func(current SolrSearchDocument) (SolrDocument, error) {
+		return SolrDocument{}, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "empty document") {
+		t.Errorf("Expected an empty document error, got %v", err)
+	}
+
+	must(sc.ReadModifyWrite("test", "1", 0, create))
+	if posts != 1 {
+		t.Errorf("Expected one update attempt, got %d", posts)
+	}
+}